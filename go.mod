@@ -9,7 +9,6 @@ require (
 	github.com/fatih/color v1.16.0
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/gorilla/websocket v1.5.1
-	golang.org/x/text v0.13.0
 )
 
 require (
@@ -0,0 +1,184 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "math"
+    "math/big"
+    "time"
+
+    "github.com/fatih/color"
+)
+
+// A sample of the price and 24h volume of the chains' coin in the default currency
+type priceSample struct {
+    Time   time.Time
+    Price  float64
+    Volume float64
+}
+
+// How long to wait at most before querying the all-time high again after failing to
+const athMaxBackoff = 6 * time.Hour
+
+// State of the price alerts, the samples of the longest window and when each alert was last sent
+// Until the all-time high is known, ath is the highest price sampled and the queries of the all-time high back off
+type priceAlerter struct {
+    samples    []priceSample
+    ath        float64
+    athKnown   bool
+    athRetry   time.Time
+    athBackoff time.Duration
+    sent       map[string]time.Time
+}
+
+// Samples the price of the chains' coin every price interval, announcing moves beyond the thresholds,
+// all-time high crossings and 24h volume spikes. An alert isn't repeated until its cooldown has passed
+func priceAlerts(resp chan MessageResponse) {
+    alerter := &priceAlerter{sent: map[string]time.Time{}}
+    ticker := time.NewTicker(priceInterval)
+    for {
+        for _, msg := range alerter.check(time.Now()) {
+            if msg.Type.Enabled && renderMessage(&msg) && isAllowedMessage(msg) {
+                resp <- msg
+            }
+        }
+        <-ticker.C
+    }
+}
+
+// Takes a sample of the price, and returns the alerts it triggers
+func (a *priceAlerter) check(now time.Time) []MessageResponse {
+    cfg := config.Config.AlertsConfig.Price
+    quote := assetQuote(PricedAsset{Denom: config.Chain.Denom, Exponent: config.Chain.Exponent, CoinGeckoID: config.Chain.CoinGeckoID}, "")
+    // Stale prices would only repeat the last sample
+    if quote.Price == 0 || quote.Stale {
+        return nil
+    }
+    sample := priceSample{Time: now, Price: quote.Price, Volume: getVolume(config.Chain.CoinGeckoID, "")}
+    a.samples = append(a.samples, sample)
+    // Keep the samples of the longest window, and of a day ago for the volume
+    history := 24 * time.Hour
+    for _, move := range cfg.Moves {
+        history = max(history, time.Duration(move.Window) * time.Minute)
+    }
+    for len(a.samples) > 0 && now.Sub(a.samples[0].Time) > history + priceInterval {
+        a.samples = a.samples[1:]
+    }
+
+    var alerts []MessageResponse
+    for _, move := range cfg.Moves {
+        window := time.Duration(move.Window) * time.Minute
+        past, ok := a.sampleBefore(now.Add(-window))
+        if !ok {
+            continue
+        }
+        change := (sample.Price - past.Price) / past.Price * 100
+        if math.Abs(change) < move.Percent {
+            continue
+        }
+        // Moves up and down have their own cooldown, so a drop right after a rise is still announced
+        direction, title := "up", "📈 Price Up 📈"
+        if change < 0 {
+            direction, title = "down", "📉 Price Down 📉"
+        }
+        if a.cooledDown(fmt.Sprintf("move %s %d", direction, move.Window), now) {
+            msg := mkPriceAlert("move", title, sample, quote.Source,
+                "Change", fmt.Sprintf("%+.2f%% in %s", change, formatWindow(move.Window)))
+            msg.Data["change"] = fmt.Sprintf("%+.2f%%", change)
+            msg.Data["window"] = formatWindow(move.Window)
+            alerts = append(alerts, msg)
+        }
+    }
+    if cfg.ATH {
+        if !a.athKnown && !now.Before(a.athRetry) {
+            if ath, err := getATH(config.Chain.CoinGeckoID, ""); err != nil {
+                a.athBackoff = min(max(a.athBackoff * 2, priceInterval), athMaxBackoff)
+                a.athRetry = now.Add(a.athBackoff)
+                log.Println(color.YellowString("Failed to get the all-time high price, retrying in %s: %v", a.athBackoff, err))
+            } else {
+                // Prices sampled while it was unknown may be higher
+                a.ath = max(a.ath, ath)
+                a.athKnown = true
+            }
+        }
+        // Without the all-time high, crossings of the highest sampled price aren't announced
+        if !a.athKnown {
+            a.ath = max(a.ath, sample.Price)
+        } else if sample.Price > a.ath {
+            previous := a.ath
+            a.ath = sample.Price
+            if a.cooledDown("ath", now) {
+                msg := mkPriceAlert("ath", "🚀 New All-Time High 🚀", sample, quote.Source,
+                    "Previous High", formatFloat(previous) + " " + currencyName(""))
+                alerts = append(alerts, msg)
+            }
+        }
+    }
+    if cfg.VolumeSpike > 0 && sample.Volume > 0 {
+        past, ok := a.sampleBefore(now.Add(-24 * time.Hour))
+        if ok && past.Volume > 0 && sample.Volume >= past.Volume * cfg.VolumeSpike && a.cooledDown("volume", now) {
+            msg := mkPriceAlert("volume", "📊 Volume Spike 📊", sample, quote.Source,
+                "Change", fmt.Sprintf("%.1fx the 24h volume of a day ago", sample.Volume / past.Volume))
+            alerts = append(alerts, msg)
+        }
+    }
+    return alerts
+}
+
+// Returns the latest sample taken at or before the time, false if there are no samples that old
+func (a *priceAlerter) sampleBefore(t time.Time) (priceSample, bool) {
+    for i := len(a.samples) - 1; i >= 0; i-- {
+        if !a.samples[i].Time.After(t) {
+            return a.samples[i], true
+        }
+    }
+    return priceSample{}, false
+}
+
+// Checks if the cooldown of the alert has passed, starting a new cooldown if it has
+func (a *priceAlerter) cooledDown(alert string, now time.Time) bool {
+    if last, ok := a.sent[alert]; ok && now.Sub(last) < time.Duration(config.Config.AlertsConfig.Price.Cooldown) * time.Minute {
+        return false
+    }
+    a.sent[alert] = now
+    return true
+}
+
+// Returns a price alert of the chains' coin, with the price and volume of the sample and the detail of the alert
+func mkPriceAlert(alert string, title string, sample priceSample, source string, name string, detail string) MessageResponse {
+    var msg MessageResponse
+    msg.Type = config.Config.MessagesConfig.PriceAlerts
+    msg.TypeName = "PriceAlerts"
+    msg.Doc.Title = title
+    msg.Doc.Add("Coin", plain(config.Chain.DisplayName))
+    msg.Doc.Add("Price", plain(formatFloat(sample.Price) + " " + currencyName("")))
+    msg.Doc.Add(name, plain(detail))
+    msg.Doc.PriceSources = []string{source}
+    msg.Data = MessageData{
+        "coin": config.Chain.DisplayName,
+        "alert": alert,
+        "price": formatFloat(sample.Price) + " " + currencyName(""),
+        "change": "",
+        "window": "",
+        "volume": "",
+    }
+    if sample.Volume > 0 {
+        volume := formatFloat(sample.Volume) + " " + currencyName("")
+        msg.Doc.Add("24h Volume", plain(volume))
+        msg.Data["volume"] = volume
+    }
+    return msg
+}
+
+// Formats a price or volume, E.G. 1234.5 becomes 1,234.50
+func formatFloat(f float64) string {
+    return formatDecimal(new(big.Rat).SetFloat64(f))
+}
+
+// Formats a window in minutes, E.G. 90 becomes 90m and 120 becomes 2h
+func formatWindow(minutes int) string {
+    if minutes % 60 == 0 {
+        return fmt.Sprintf("%dh", minutes / 60)
+    }
+    return fmt.Sprintf("%dm", minutes)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+    "math/big"
+    "strings"
+)
+
+// Significant digits shown for amounts below 1, so dust isn't rounded to 0.00
+const significantDigits = 4
+
+// Splits an amount like 1000nund, and returns 1000 and nund
+// The amount is exact, however large it is
+func splitAmountDenom(amount string) (*big.Int, string) {
+    if amount == "" {
+        return new(big.Int), "UnknownDenom"
+    }
+    i := 0
+    for i < len(amount) && amount[i] >= '0' && amount[i] <= '9' {
+        i++
+    }
+    value, ok := new(big.Int).SetString(amount[:i], 10)
+    if !ok {
+        value = new(big.Int)
+    }
+    return value, amount[i:]
+}
+
+// Splits a list of coins like 100nund,5ibc/27394... into its coins, E.G. 100nund and 5ibc/27394...
+func splitCoins(amounts string) []string {
+    var coins []string
+    for _, coin := range strings.Split(amounts, ",") {
+        if coin = strings.TrimSpace(coin); coin != "" {
+            coins = append(coins, coin)
+        }
+    }
+    return coins
+}
+
+// Converts an amount in the base denom to the display denom, E.G. 1000000000nund with an exponent of 9 is 1 FUND
+func toDisplay(amount *big.Int, exponent int) *big.Rat {
+    exp := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+    return new(big.Rat).SetFrac(amount, exp)
+}
+
+// Returns the value of an amount in the display denom, in the configured currency
+func fiatValue(display *big.Rat, price float64) *big.Rat {
+    p := new(big.Rat)
+    if p.SetFloat64(price) == nil {
+        return new(big.Rat)
+    }
+    return p.Mul(p, display)
+}
+
+// Formats a decimal with thousands separators and two decimals, E.G. 1234.5 becomes 1,234.50
+// Values below 1 keep their significant digits instead, E.G. 0.000042 stays 0.000042
+func formatDecimal(r *big.Rat) string {
+    decimals := 2
+    abs := new(big.Rat).Abs(r)
+    if abs.Sign() != 0 && abs.Cmp(big.NewRat(1, 1)) < 0 {
+        // Count the zeros after the decimal point
+        frac := abs.FloatString(40)[2:]
+        decimals = len(frac) - len(strings.TrimLeft(frac, "0")) + significantDigits
+    }
+    whole, frac, _ := strings.Cut(r.FloatString(decimals), ".")
+    for len(frac) > 2 && strings.HasSuffix(frac, "0") {
+        frac = frac[:len(frac)-1]
+    }
+    sign := ""
+    if strings.HasPrefix(whole, "-") {
+        sign, whole = "-", whole[1:]
+    }
+    var grouped string
+    for i, c := range whole {
+        if i > 0 && (len(whole)-i)%3 == 0 {
+            grouped += ","
+        }
+        grouped += string(c)
+    }
+    return sign + grouped + "." + frac
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+    "math/big"
+    "testing"
+)
+
+func TestSplitAmountDenom(t *testing.T) {
+    tests := []struct {
+        amount string
+        value  string
+        denom  string
+    }{
+        {"1000nund", "1000", "nund"},
+        {"5ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", "5", "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2"},
+        // Amounts beyond 64 bits stay exact
+        {"123456789012345678901234567890nund", "123456789012345678901234567890", "nund"},
+        {"nund", "0", "nund"},
+        {"100", "100", ""},
+        {"", "0", "UnknownDenom"},
+    }
+    for _, tt := range tests {
+        value, denom := splitAmountDenom(tt.amount)
+        if value.String() != tt.value || denom != tt.denom {
+            t.Errorf("splitAmountDenom(%q) = %s, %q, want %s, %q", tt.amount, value, denom, tt.value, tt.denom)
+        }
+    }
+}
+
+func TestSplitCoins(t *testing.T) {
+    tests := []struct {
+        amounts string
+        coins   []string
+    }{
+        {"100nund", []string{"100nund"}},
+        {"100nund,5uatom", []string{"100nund", "5uatom"}},
+        {" 100nund , 5uatom ,", []string{"100nund", "5uatom"}},
+        {"", nil},
+    }
+    for _, tt := range tests {
+        coins := splitCoins(tt.amounts)
+        if len(coins) != len(tt.coins) {
+            t.Errorf("splitCoins(%q) = %q, want %q", tt.amounts, coins, tt.coins)
+            continue
+        }
+        for i := range coins {
+            if coins[i] != tt.coins[i] {
+                t.Errorf("splitCoins(%q) = %q, want %q", tt.amounts, coins, tt.coins)
+                break
+            }
+        }
+    }
+}
+
+func TestToDisplay(t *testing.T) {
+    tests := []struct {
+        amount   string
+        exponent int
+        want     string
+    }{
+        {"1000000000", 9, "1"},
+        {"1500000", 6, "3/2"},
+        {"1", 18, "1/1000000000000000000"},
+        {"42", 0, "42"},
+    }
+    for _, tt := range tests {
+        amount, _ := new(big.Int).SetString(tt.amount, 10)
+        if got := toDisplay(amount, tt.exponent).RatString(); got != tt.want {
+            t.Errorf("toDisplay(%s, %d) = %s, want %s", tt.amount, tt.exponent, got, tt.want)
+        }
+    }
+}
+
+func TestFormatDecimal(t *testing.T) {
+    tests := []struct {
+        value string
+        want  string
+    }{
+        {"0", "0.00"},
+        {"100", "100.00"},
+        {"1234.5", "1,234.50"},
+        {"1234567.891", "1,234,567.89"},
+        {"999.999", "1,000.00"},
+        {"-1234.5", "-1,234.50"},
+        {"1000000000000000000000000000000", "1,000,000,000,000,000,000,000,000,000,000.00"},
+        // Values below 1 keep their significant digits
+        {"0.5", "0.50"},
+        {"0.123456", "0.1235"},
+        {"0.000042", "0.000042"},
+        {"0.0000123456", "0.00001235"},
+        {"-0.0005", "-0.0005"},
+        {"1/3", "0.3333"},
+    }
+    for _, tt := range tests {
+        r, ok := new(big.Rat).SetString(tt.value)
+        if !ok {
+            t.Fatalf("invalid value %s", tt.value)
+        }
+        if got := formatDecimal(r); got != tt.want {
+            t.Errorf("formatDecimal(%s) = %s, want %s", tt.value, got, tt.want)
+        }
+    }
+}
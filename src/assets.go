@@ -0,0 +1,46 @@
+package main
+
+import (
+    "strings"
+)
+
+// An asset of the home chain other than its staking coin, E.G. a token factory denom or a secondary native coin
+type AssetData struct {
+    Denom         string
+    DisplayName   string
+    Exponent      int
+    CoinGeckoID   string
+}
+
+// Returns the assets of the asset list, except the chains' staking coin which is configured separately
+// The exponent is the one of the display denom unit, and the display name is the assets' symbol
+func loadAssets(assets AssetsResponse, stakingDenom string) []AssetData {
+    var loaded []AssetData
+    for _, asset := range assets.Assets {
+        if asset.Denom == "" || asset.Denom == stakingDenom {
+            continue
+        }
+        data := AssetData{Denom: asset.Denom, DisplayName: strings.ToUpper(asset.Coin)}
+        if data.DisplayName == "" {
+            data.DisplayName = strings.ToUpper(asset.Display)
+        }
+        for _, unit := range asset.DenomUnits {
+            if unit.Denom == asset.Display {
+                data.Exponent = unit.Exponent
+            }
+        }
+        data.CoinGeckoID = asset.CoingeckoID
+        loaded = append(loaded, data)
+    }
+    return loaded
+}
+
+// Returns the asset of the home chain with the given denom
+func findAsset(denom string) (*AssetData, bool) {
+    for i := range config.Chain.Assets {
+        if config.Chain.Assets[i].Denom == denom {
+            return &config.Chain.Assets[i], true
+        }
+    }
+    return nil, false
+}
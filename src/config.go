@@ -6,7 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -24,13 +24,54 @@ type ConfigFile struct {
     ICNSConfig        ICNSConfig `toml:"icns"` 
     AddressesConfig   AddressesConfig `toml:"address"`
     MessagesConfig    MessagesConfig `toml:"messages"`
+    SupplyConfig      SupplyConfig `toml:"supply"`
+    DistributionsConfig DistributionsConfig `toml:"distributions"`
+    ExplorerConfig    ExplorerConfig `toml:"explorer"`
+    PricesConfig      PricesConfig `toml:"prices"`
+    AlertsConfig      AlertsConfig `toml:"alerts"`
+    ExpressionsConfig ExpressionsConfig `toml:"expressions"`
 }
 type ClientsConfig struct{
-    Clients    []string `toml:"clients"`
-    TgAPI      string   `toml:"telegram-api"`
-    TgChatIDs  []string `toml:"telegram-chat-ids"`
-    DscAPI     string   `toml:"discord-api"`
-    DscChatIDs []string `toml:"discord-chat-ids"`
+    Clients     []string `toml:"clients"`
+    TgAPI       string   `toml:"telegram-api"`
+    TgChatIDs   []string `toml:"telegram-chat-ids"`
+    TgParseMode string   `toml:"telegram-parse-mode"`
+    DscAPI      string   `toml:"discord-api"`
+    DscChatIDs  []string `toml:"discord-chat-ids"`
+    ChatCurrencies map[string]string `toml:"chat-currencies"`
+}
+type ExplorerConfig struct {
+    Use            []string                       `toml:"use"`
+    Custom         []Explorer                     `toml:"custom"`
+    Chains         map[string]ChainExplorerConfig `toml:"chains"`
+}
+// Overrides of the explorers of a chain, keyed by the chains' name in the chain registry
+type ChainExplorerConfig struct {
+    Path           string   `toml:"path"`
+    Use            []string `toml:"use"`
+}
+type PricesConfig struct {
+    Sources        []string           `toml:"sources"`
+    File           string             `toml:"file"`
+    MaxAge         int                `toml:"max-age"`
+    Stale          string             `toml:"stale"`
+    Osmosis        OsmosisPriceConfig `toml:"osmosis"`
+    Assets         []AssetPriceConfig `toml:"assets"`
+}
+type OsmosisPriceConfig struct {
+    Rest           string `toml:"rest"`
+    TWAP           int    `toml:"twap"`
+}
+// Price settings of an asset, keyed by its base denom on its origin chain
+type AssetPriceConfig struct {
+    Denom            string   `toml:"denom"`
+    Sources          []string `toml:"sources"`
+    Static           map[string]float64 `toml:"static"`
+    OsmosisPool      uint64   `toml:"osmosis-pool"`
+    OsmosisDenom     string   `toml:"osmosis-denom"`
+    QuoteDenom       string   `toml:"osmosis-quote-denom"`
+    QuoteExponent    int      `toml:"osmosis-quote-exponent"`
+    QuoteCoinGeckoID string   `toml:"osmosis-quote-coin-gecko-id"`
 }
 type ChainConfig struct {
     Name string
@@ -60,18 +101,88 @@ type AddressConfig struct {
     Name string `toml:"name"` 
     Addr string `toml:"addr"` 
 }
+type SupplyConfig struct {
+    Enabled        bool     `toml:"enable"`
+    BurnAddresses  []string `toml:"burn-addresses"`
+    ReportInterval int      `toml:"report-interval"`
+}
+type AlertsConfig struct {
+    Price          PriceAlertsConfig `toml:"price"`
+}
+type PriceAlertsConfig struct {
+    Enabled        bool              `toml:"enable"`
+    Moves          []PriceMoveConfig `toml:"moves"`
+    ATH            bool              `toml:"ath"`
+    VolumeSpike    float64           `toml:"volume-spike"`
+    Cooldown       int               `toml:"cooldown"`
+}
+// A price move to alert on, of at least the percentage within the window in minutes
+type PriceMoveConfig struct {
+    Percent        float64 `toml:"percent"`
+    Window         int     `toml:"window"`
+}
+// Filter expressions, the expression every message has to match, the expressions of the chats keyed by their
+// chat ID, and the named lists of the expressions
+type ExpressionsConfig struct {
+    Filter         string              `toml:"filter"`
+    Chats          map[string]string   `toml:"chats"`
+    Lists          map[string][]string `toml:"lists"`
+}
+type DistributionsConfig struct {
+    Enabled        bool     `toml:"enable"`
+    Window         int      `toml:"window"`
+    MinTransfers   int      `toml:"min-transfers"`
+    TopRecipients  int      `toml:"top-recipients"`
+    BlockTimeout   int      `toml:"block-timeout"`
+}
 type MessageConfig struct {
     Enabled        bool     `toml:"enable"`
     Filter         string   `toml:"filter"`
     WhiteBlackList []string `toml:"list"`
     AmountFilter   bool     `toml:"amount-filter"`
     Threshold      float64  `toml:"threshold"`
+    Template       string   `toml:"template"`
+    Color          string   `toml:"discord-color"`
+    UnknownPrice   string   `toml:"unknown-price"`
+    NativeThreshold float64 `toml:"native-threshold"`
+    DenomThresholds map[string]float64 `toml:"denom-thresholds"`
+    ThresholdMode  string   `toml:"threshold-mode"`
+}
+// Currency codes, a single currency or a list of them
+type Currencies []string
+
+func (c *Currencies) UnmarshalTOML(value interface{}) error {
+    switch v := value.(type) {
+    case string:
+        *c = Currencies{v}
+    case []interface{}:
+        *c = nil
+        for _, currency := range v {
+            code, ok := currency.(string)
+            if !ok {
+                return fmt.Errorf("currency must be a string or a list of strings")
+            }
+            *c = append(*c, code)
+        }
+    default:
+        return fmt.Errorf("currency must be a string or a list of strings")
+    }
+    return nil
+}
+type IBCFailedConfig struct {
+    MessageConfig
+    EditOriginal   bool     `toml:"edit-original"`
 }
 type MessagesConfig struct {
-    Currency        string        `toml:"currency"`
+    Currency        Currencies    `toml:"currency"`
+    ShowDenomPath   bool          `toml:"show-denom-path"`
+    UnknownPrice    string        `toml:"unknown-price"`
     Transfers       MessageConfig `toml:"transfers"`
+    MultiSend       MessageConfig `toml:"multi-send"`
+    Distributions   MessageConfig `toml:"distributions"`
     IBCIn           MessageConfig `toml:"ibc-transfers-in"`
     IBCOut          MessageConfig `toml:"ibc-transfers-out"`
+    IBCFailed       IBCFailedConfig `toml:"ibc-transfers-failed"`
     Rewards         MessageConfig `toml:"withdraw-rewards"`
     Commission      MessageConfig `toml:"withdraw-commission"`
     Delegations     MessageConfig `toml:"delegations"`
@@ -83,43 +194,61 @@ type MessagesConfig struct {
     TransferAccount MessageConfig `toml:"transfer-account"`
     TransferDomain  MessageConfig `toml:"transfer-domain"`
     DeleteAccount   MessageConfig `toml:"delete-account"`
+    RenewAccount     MessageConfig `toml:"renew-account"`
+    RenewDomain      MessageConfig `toml:"renew-domain"`
+    ReplaceResources MessageConfig `toml:"replace-resources"`
+    ReplaceMetadata  MessageConfig `toml:"replace-metadata"`
+    CreateEscrow     MessageConfig `toml:"create-escrow"`
+    UpdateEscrow     MessageConfig `toml:"update-escrow"`
+    TransferToEscrow MessageConfig `toml:"transfer-to-escrow"`
+    RefundEscrow     MessageConfig `toml:"refund-escrow"`
+    Burns            MessageConfig `toml:"burns"`
+    Mints            MessageConfig `toml:"mints"`
+    SupplyReport     MessageConfig `toml:"supply-report"`
+    PriceAlerts      MessageConfig `toml:"price-alerts"`
+    Other            MessageConfig `toml:"other"`
+    Custom           []CustomMessageConfig `toml:"custom"`
+}
+// A message type declared in the config, for actions of modules without a built-in message type
+type CustomMessageConfig struct {
+    MessageConfig
+    Name           string            `toml:"name"`
+    Action         string            `toml:"action"`
+    Title          string            `toml:"title"`
+    Required       []string          `toml:"required"`
+    Roles          map[string]string `toml:"roles"`
 }
 
 
 type ChainData struct {
+    ChainName         string
+    ChainID           string
+    PrettyName        string
     DisplayName       string 
     Denom             string
     Exponent          int 
     Prefix            string
+    Rest              string
     ExplorerPath      string
-    CoinGeckoData     CoinGeckoData
-}
-type CoinGeckoData struct {
-    ID              string
-    Price           *float64
-    Active          bool
-    Data            CoinGeckoResponse
+    Explorers         []Explorer
+    CoinGeckoID       string
+    // Other assets of the chain, only loaded for the home chain
+    Assets            []AssetData
 }
 type ConnectionData struct {
     Rest            string
     Websocket       string
     ICNS            string
 }
-type ExplorerData struct {
-    Base            string
-    Account         string
-    Validator       string
-    TX              string
-}
 
 // Runtime Config
 type Config struct {
     Config          ConfigFile
 
-    Currency        string
+    // Currency codes to show amounts in, the first one is the default
+    Currencies      []string
     Chain           ChainData
     Connections     ConnectionData
-    Explorer        ExplorerData
     OtherChains     []ChainData
 }
 
@@ -132,15 +261,7 @@ var (
 )
 
 func (cfg *Config) parseConfig(filePath string) {
-    if strings.HasSuffix(filePath, "config.toml") {
-        filePath = strings.TrimSuffix(filePath, "config.toml")
-    }
-    ensureTrailingSlash(&filePath)
-    if _, err := toml.DecodeFile(filePath + "config.toml", &configfile); err != nil {
-        log.Fatal(color.RedString("Error parsing config.toml file, verify your configuation:", err))
-    }
-
-    cfg.Config = configfile
+    cfg.readConfig(filePath)
 
 
     // Grab the first available Rest URL for ICNS from the chain registry, if default = true
@@ -199,31 +320,26 @@ func (cfg *Config) parseConfig(filePath string) {
             log.Fatal(color.RedString("Failed to get the chain.json from the chain registry, verify your chains' name matches the entry from the chain registry"))
         }
         cfg.Chain = ChainData {
+            ChainName: chain.ChainName,
+            ChainID: chain.ChainID,
+            PrettyName: chain.PrettyName,
             DisplayName: assets.Assets[0].Display, 
             Denom: assets.Assets[0].DenomUnits[0].Denom,
             Exponent: assets.Assets[0].DenomUnits[1].Exponent,
             Prefix: chain.Bech32Prefix,
-            ExplorerPath: chain.PrettyName,
         }
-        cfg.Chain.CoinGeckoData.ID = assets.Assets[0].CoingeckoID
+        cfg.Chain.CoinGeckoID = assets.Assets[0].CoingeckoID
     } else {
-        cfg.Chain = ChainData {
-            DisplayName: configfile.ChainInfoConfig.Coin, 
-            Denom: configfile.ChainInfoConfig.Denom,
-            Exponent: configfile.ChainInfoConfig.Exponent,
-            Prefix: configfile.ChainInfoConfig.Bech32Prefix,
-            ExplorerPath: configfile.ChainInfoConfig.PrettyName,
-        }
-        cfg.Chain.CoinGeckoData.ID = configfile.ChainInfoConfig.CoinGeckoID
-    }
-    // Set the currency type
-    r := reflect.ValueOf(&cfg.Chain.CoinGeckoData.Data.MarketData.CurrentPrice).Elem()
-    for i := 0; i < r.NumField(); i++ {
-        if strings.ToLower(configfile.MessagesConfig.Currency) == strings.ToLower(r.Type().Field(i).Name) {
-            cfg.Currency = strings.ToUpper(r.Type().Field(i).Name)
-            cfg.Chain.CoinGeckoData.Price = r.Field(i).Addr().Interface().(*float64)
+        cfg.Chain = configChainData()
+        // The other assets are optional when the chain info is set manually
+        if err := getData(
+            fmt.Sprintf("https://raw.githubusercontent.com/cosmos/chain-registry/master/%s/assetlist.json", configfile.ChainConfig.Name),
+            &assets); err != nil {
+            log.Println(color.YellowString("Failed to get the assetlist.json from the chain registry, only the chains' coin will be shown"))
         }
     }
+    // Load the other assets of the chain, E.G. token factory denoms
+    cfg.Chain.Assets = loadAssets(assets, cfg.Chain.Denom)
     // Grab OtherChains Configurations
     // TODO Have the program restart ever day or so, to refresh the chain data, or autofresh this
     log.Println(color.BlueString("Querying Asset and Chain data for other available chains..."))
@@ -245,13 +361,15 @@ func (cfg *Config) parseConfig(filePath string) {
             log.Println(color.YellowString("Failed to get Chain Data for: " + c + " moving to next..."))
             continue
         } else {
-            // Fixes for specific chains, that don't adhear their paths to the chain registry
-            if chain.PrettyName == "Cosmos Hub" {
-                data.ExplorerPath = "Cosmos" 
-            } else {
-                data.ExplorerPath = strings.ReplaceAll(chain.PrettyName," ","-")
-            }
+            data.ExplorerPath = explorerPath(chain.ChainName)
             data.Prefix = chain.Bech32Prefix
+            data.ChainName = chain.ChainName
+            data.ChainID = chain.ChainID
+            data.PrettyName = chain.PrettyName
+            if len(chain.Apis.Rest) > 0 {
+                data.Rest = chain.Apis.Rest[0].Address
+                ensureTrailingSlash(&data.Rest)
+            }
             if data.Prefix == "" || data.ExplorerPath == "" {
                 log.Println(color.YellowString("Failed to get Chain Data for: " + c + " moving to next..."))
                 continue
@@ -265,7 +383,7 @@ func (cfg *Config) parseConfig(filePath string) {
             continue
         } else {
             // Verify we can grab the correct DisplayName, Exponent, and Denom from the list
-            data.CoinGeckoData.ID = ass.Assets[0].CoingeckoID
+            data.CoinGeckoID = ass.Assets[0].CoingeckoID
             for _, denom := range(ass.Assets[0].DenomUnits) {
                 switch denom.Denom{
                 case ass.Assets[0].Display:
@@ -275,7 +393,7 @@ func (cfg *Config) parseConfig(filePath string) {
                     data.Denom = strings.ToLower(ass.Assets[0].Denom)
                 }
             }
-            if data.DisplayName == "" || data.Denom == "" || data.CoinGeckoData.ID == "" {
+            if data.DisplayName == "" || data.Denom == "" || data.CoinGeckoID == "" {
                 log.Println(color.YellowString("Failed to get Asset Data for: " + c + " moving to next..."))
                 continue 
             }
@@ -288,32 +406,264 @@ func (cfg *Config) parseConfig(filePath string) {
     } else {
         log.Println(color.YellowString(fmt.Sprintf("No chains could be queried")))
     }
-    for i := range(cfg.OtherChains) {
-        chain := &cfg.OtherChains[i]
-        r := reflect.ValueOf(&chain.CoinGeckoData.Data.MarketData.CurrentPrice).Elem()
-        for i := 0; i < r.NumField(); i++ {
-            if strings.ToLower(configfile.MessagesConfig.Currency) == strings.ToLower(r.Type().Field(i).Name) {
-                chain.CoinGeckoData.Price = r.Field(i).Addr().Interface().(*float64)
-            }
-        }
+    registerHandlers()
+    cfg.validateConfig(false)
+    cfg.testConnections()
+}
+
+// Reads the config.toml in the directory or at the path, the cached denom traces are next to it
+func (cfg *Config) readConfig(filePath string) {
+    if strings.HasSuffix(filePath, "config.toml") {
+        filePath = strings.TrimSuffix(filePath, "config.toml")
     }
-    cfg.validateConfig()
+    ensureTrailingSlash(&filePath)
+    if _, err := toml.DecodeFile(filePath + "config.toml", &configfile); err != nil {
+        log.Fatal(color.RedString("Error parsing config.toml file, verify your configuation:", err))
+    }
+
+    // Relative paths are next to the config, set before the config is copied
+    if file := configfile.PricesConfig.File; file != "" && !strings.HasPrefix(file, "/") {
+        configfile.PricesConfig.File = filePath + file
+    }
+    cfg.Config = configfile
+    loadDenomTraces(filePath + "denom_traces.json")
 }
-func (cfg *Config) validateConfig(){
+
+// Parses the config without querying the chain registry, the chain or CoinGecko, used to test filter expressions
+// offline. The chains' coin comes from [chaininfo], IBC tokens are unknown and prices only come from the static
+// and file price sources
+func (cfg *Config) parseOfflineConfig(filePath string) {
+    cfg.readConfig(filePath)
+    cfg.Chain = configChainData()
+    if cfg.Chain.Denom == "" {
+        log.Println(color.YellowString("No denom in [chaininfo], amounts of the chains' coin will be unknown"))
+    }
+    registerHandlers()
+    cfg.validateConfig(true)
+}
+
+// Returns the chain info set in [chaininfo]
+func configChainData() ChainData {
+    return ChainData {
+        ChainName: configfile.ChainConfig.Name,
+        PrettyName: configfile.ChainInfoConfig.PrettyName,
+        DisplayName: configfile.ChainInfoConfig.Coin, 
+        Denom: configfile.ChainInfoConfig.Denom,
+        Exponent: configfile.ChainInfoConfig.Exponent,
+        Prefix: configfile.ChainInfoConfig.Bech32Prefix,
+        CoinGeckoID: configfile.ChainInfoConfig.CoinGeckoID,
+    }
+}
+// Validates the config, offline skips the checks which need the network or the chain registry
+func (cfg *Config) validateConfig(offline bool){
     log.Println(color.BlueString("Validating Config..."))
     // Confirm there is no empty data for these fields
     if len(cfg.Config.ClientsConfig.Clients) == 0 {
         log.Fatal(color.RedString("No client selected, check your config."))
     }
-    if cfg.Currency == "" {
+    // Currencies, checked against the ones CoinGecko quotes when it can be reached
+    if len(cfg.Config.MessagesConfig.Currency) == 0 {
         log.Fatal(color.RedString("Invalid Currency Type, Check your config"))
     }
+    cfg.Currencies = nil
+    for _, currency := range cfg.Config.MessagesConfig.Currency {
+        cfg.Currencies = append(cfg.Currencies, strings.ToLower(currency))
+    }
+    if offline {
+        log.Println(color.YellowString("Offline, not checking the currencies"))
+    } else if supported, err := supportedCurrencies(); err != nil {
+        log.Println(color.YellowString("Could not query the currencies CoinGecko supports, not checking the currencies: %v", err))
+    } else {
+        for _, currency := range cfg.Currencies {
+            if !slices.Contains(supported, currency) {
+                log.Fatal(color.RedString("Invalid Currency Type " + currency + ", Check your config"))
+            }
+        }
+    }
+    for chat, currency := range cfg.Config.ClientsConfig.ChatCurrencies {
+        cfg.Config.ClientsConfig.ChatCurrencies[chat] = strings.ToLower(currency)
+        if !slices.Contains(cfg.Currencies, strings.ToLower(currency)) {
+            log.Fatal(color.RedString("The currency of chat " + chat + " isn't one of the currencies in [messages], Check your config"))
+        }
+    }
+    switch cfg.Config.ClientsConfig.TgParseMode {
+    case "":
+        cfg.Config.ClientsConfig.TgParseMode = "html"
+    case "html", "markdownv2":
+    default:
+        log.Fatal(color.RedString("Invalid telegram-parse-mode, use html or markdownv2. Check your config"))
+    }
+    if cfg.Config.DistributionsConfig.Window <= 0 {
+        cfg.Config.DistributionsConfig.Window = 5
+    }
+    if cfg.Config.DistributionsConfig.MinTransfers <= 0 {
+        cfg.Config.DistributionsConfig.MinTransfers = 5
+    }
+    if cfg.Config.DistributionsConfig.TopRecipients <= 0 {
+        cfg.Config.DistributionsConfig.TopRecipients = 5
+    }
+    if cfg.Config.DistributionsConfig.BlockTimeout <= 0 {
+        cfg.Config.DistributionsConfig.BlockTimeout = 10
+    }
+    // Otherwise transfers would be held back without ever being collapsed
+    if cfg.Config.DistributionsConfig.Enabled && !cfg.Config.MessagesConfig.Distributions.Enabled {
+        log.Fatal(color.RedString("[distributions] is enabled but [messages.distributions] is disabled, enable both or neither. Check your config"))
+    }
+    for _, move := range cfg.Config.AlertsConfig.Price.Moves {
+        if move.Percent <= 0 || move.Window <= 0 {
+            log.Fatal(color.RedString("Price alert moves need a percent and a window above 0, check your config"))
+        }
+    }
+    if cfg.Config.AlertsConfig.Price.Cooldown <= 0 {
+        cfg.Config.AlertsConfig.Price.Cooldown = 60
+    }
+    // What the amount filters do with unknown prices, the message types use the default unless they set their own
+    if cfg.Config.MessagesConfig.UnknownPrice == "" {
+        cfg.Config.MessagesConfig.UnknownPrice = "block"
+    }
+    for name, t := range messageTypes {
+        if t.Config.UnknownPrice == "" {
+            t.Config.UnknownPrice = cfg.Config.MessagesConfig.UnknownPrice
+        }
+        switch t.Config.UnknownPrice {
+        case "pass", "block", "native":
+        default:
+            log.Fatal(color.RedString("Invalid unknown-price for message type " + name + ", use pass, block or native. Check your config"))
+        }
+        switch t.Config.ThresholdMode {
+        case "":
+            t.Config.ThresholdMode = "or"
+        case "or", "and":
+        default:
+            log.Fatal(color.RedString("Invalid threshold-mode for message type " + name + ", use or or and. Check your config"))
+        }
+    }
+    // Denom thresholds are keyed by the display name or base denom of a coin of this chain or the other chains,
+    // which aren't known offline
+    known := []string{strings.ToLower(cfg.Chain.DisplayName), strings.ToLower(cfg.Chain.Denom)}
+    for _, asset := range cfg.Chain.Assets {
+        known = append(known, strings.ToLower(asset.DisplayName), strings.ToLower(asset.Denom))
+    }
+    for _, other := range cfg.OtherChains {
+        known = append(known, strings.ToLower(other.DisplayName), strings.ToLower(other.Denom))
+    }
+    for name, t := range messageTypes {
+        for denom := range t.Config.DenomThresholds {
+            if !offline && !slices.Contains(known, strings.ToLower(denom)) {
+                log.Fatal(color.RedString("Unknown denom " + denom + " in the denom-thresholds of message type " + name +
+                    ", use the display name or base denom of a coin of the chain or of the chains it has IBC tokens of. Check your config"))
+            }
+        }
+    }
+    for name, t := range messageTypes {
+        if t.Config.Color == "" {
+            continue
+        }
+        if _, err := parseColor(t.Config.Color); err != nil {
+            log.Fatal(color.RedString("Invalid discord-color for message type " + name + ", use a hex color like #5865F2"))
+        }
+    }
+    // Compile the rules of the lists, which can match the fields of the messages' template
+    for name, t := range messageTypes {
+        var rules []FilterRule
+        for _, item := range t.Config.WhiteBlackList {
+            rule, err := parseFilterRule(item, t.Fields)
+            if err != nil {
+                log.Fatal(color.RedString(fmt.Sprintf("Invalid list item %q for message type %s: %s", item, name, err)))
+            }
+            if warning, ok := rule.warning(); ok && (t.Config.Filter == "blacklist" || t.Config.Filter == "whitelist") {
+                log.Println(color.YellowString(fmt.Sprintf("List item %q of message type %s %s", item, name, warning)))
+            }
+            rules = append(rules, rule)
+        }
+        filterRules[name] = rules
+    }
+    // Compile the filter expressions, which can use the fields of every message type
+    fields := expressionFields()
+    if cfg.Config.ExpressionsConfig.Filter != "" {
+        expr, err := compileExpression(cfg.Config.ExpressionsConfig.Filter, fields, cfg.Config.ExpressionsConfig.Lists)
+        if err != nil {
+            log.Fatal(color.RedString(fmt.Sprintf("Invalid filter expression %q: %s\nAvailable fields: %s",
+                cfg.Config.ExpressionsConfig.Filter, err, strings.Join(fields, ", "))))
+        }
+        filterExpression = expr
+    }
+    for chat, text := range cfg.Config.ExpressionsConfig.Chats {
+        expr, err := compileExpression(text, fields, cfg.Config.ExpressionsConfig.Lists)
+        if err != nil {
+            log.Fatal(color.RedString(fmt.Sprintf("Invalid filter expression %q of chat %s: %s\nAvailable fields: %s",
+                text, chat, err, strings.Join(fields, ", "))))
+        }
+        chatExpressions[chat] = expr
+    }
+    // Compile the message templates, executing them against example data to catch unknown fields
+    for name, t := range messageTypes {
+        if t.Config.Template == "" {
+            continue
+        }
+        tmpl, err := parseTemplate(name, t.Config.Template)
+        if err == nil {
+            err = checkTemplate(tmpl, t.Fields)
+        }
+        if err != nil {
+            log.Fatal(color.RedString(fmt.Sprintf("Invalid template for message type %s: %s\nAvailable fields: %s",
+                name, err, strings.Join(t.Fields, ", "))))
+        }
+        templates[name] = tmpl
+    }
+    // Price sources, the Osmosis pools are quoted in USDC by default
+    prices := &cfg.Config.PricesConfig
+    if len(prices.Sources) == 0 {
+        prices.Sources = []string{"coingecko"}
+    }
+    if prices.Osmosis.Rest == "" {
+        prices.Osmosis.Rest = "https://lcd.osmosis.zone/"
+    }
+    ensureTrailingSlash(&prices.Osmosis.Rest)
+    switch prices.Stale {
+    case "":
+        prices.Stale = "mark"
+    case "mark", "hide":
+    default:
+        log.Fatal(color.RedString("Invalid stale setting in [prices], use mark or hide. Check your config"))
+    }
+    priceProviders["file"].(*fileProvider).file = prices.File
+    for i := range prices.Assets {
+        asset := &prices.Assets[i]
+        if asset.Denom == "" {
+            log.Fatal(color.RedString("Every [[prices.assets]] needs a denom, check your config"))
+        }
+        if asset.OsmosisPool != 0 {
+            if asset.OsmosisDenom == "" {
+                log.Fatal(color.RedString("Osmosis prices of " + asset.Denom + " need an osmosis-denom, check your config"))
+            }
+            if asset.QuoteDenom == "" {
+                asset.QuoteDenom = "ibc/498A0751C798A0D9A389AA3691123DADA57DAA4FE165D5C75894505B876BA6E4"
+                asset.QuoteExponent = 6
+            }
+            if asset.QuoteCoinGeckoID == "" {
+                asset.QuoteCoinGeckoID = "usd-coin"
+            }
+        }
+    }
+    checkSources := func(sources []string) {
+        for _, source := range sources {
+            if _, ok := priceProviders[source]; !ok {
+                log.Fatal(color.RedString("Unknown price source " + source + ", use coingecko, osmosis, static or file. Check your config"))
+            }
+        }
+    }
+    checkSources(prices.Sources)
+    for _, asset := range prices.Assets {
+        checkSources(asset.Sources)
+    }
     // Format information
     cfg.Chain.DisplayName = strings.ToUpper(cfg.Chain.DisplayName)
     cfg.Chain.Denom = strings.ToLower(cfg.Chain.Denom)
     cfg.Chain.Prefix = strings.ToLower(cfg.Chain.Prefix)
     ensureTrailingSlash(&cfg.Connections.Rest)
     ensureTrailingSlash(&cfg.Connections.ICNS)
+    cfg.Chain.ExplorerPath = explorerPath(cfg.Chain.ChainName)
     ensureNoSpaces(&cfg.Chain.ExplorerPath)
     u, _ := url.Parse(cfg.Connections.Websocket)
     for i, char := range u.Host {
@@ -323,12 +673,28 @@ func (cfg *Config) validateConfig(){
         }
     }
 
-    // Set URL Pathings
-    cfg.Explorer.Base = "https://ping.pub/"
-    cfg.Explorer.Account = cfg.Explorer.Base + cfg.Chain.ExplorerPath + "/account/"
-    cfg.Explorer.Validator = cfg.Explorer.Base + cfg.Chain.ExplorerPath + "/staking/"
-    cfg.Explorer.TX = cfg.Explorer.Base + cfg.Chain.ExplorerPath + "/tx/"
+    // Set the explorers of the chains, other chains use the explorers of the home chain unless overridden
+    explorers := cfg.Config.ExplorerConfig
+    for _, explorer := range explorers.Custom {
+        if explorer.Name == "" || explorer.TX == "" {
+            log.Fatal(color.RedString("Custom explorers need a name and a tx URL, check your config"))
+        }
+    }
+    cfg.Chain.Explorers = resolveExplorers(explorers.Use, explorers.Custom)
+    if override, ok := explorers.Chains[cfg.Chain.ChainName]; ok && len(override.Use) > 0 {
+        cfg.Chain.Explorers = resolveExplorers(override.Use, explorers.Custom)
+    }
+    for i := range cfg.OtherChains {
+        chain := &cfg.OtherChains[i]
+        chain.Explorers = cfg.Chain.Explorers
+        if override, ok := explorers.Chains[chain.ChainName]; ok && len(override.Use) > 0 {
+            chain.Explorers = resolveExplorers(override.Use, explorers.Custom)
+        }
+    }
+}
 
+// Tests the ICNS, rest and websocket URLs, trying the next URLs of the chain registry when they are the default
+func (cfg *Config) testConnections() {
     // Begin Testing URL connections
     log.Println(color.BlueString("Testing ICNS URL..."))
     client := &http.Client{Timeout: 10 * time.Second}
@@ -444,6 +810,9 @@ discord-api = ""
 # example: discord-chat-ids = [ "1125944525457975326", "1125944523659096234"]
 discord-chat-ids = [ "" ]
 
+# Optional currency per chat, one of the currencies in [messages]. Other chats use the first currency
+# example: chat-currencies = { "@MyAwesomeChannel" = "eur", "1125944525457975326" = "cad" }
+
 # Telegram bot token given by the botfather
 # example: telegram-api = "5750057848:AAGb4KvbF6FP6-1GmV5Kaun8WSukLpePLXF"
 telegram-api = ""
@@ -452,6 +821,11 @@ telegram-api = ""
 # example: telegram-chat-ids = [ "@MyAwesomeChannel", "@MyAwesomeChannel2"]
 telegram-chat-ids = [ "" ]
 
+# How messages are formatted for telegram, html or markdownv2
+# Names and memos are escaped for the parse mode, so they are shown as they are on chain
+# example: telegram-parse-mode = "markdownv2"
+telegram-parse-mode = "html"
+
 [chain]
 # The name of the chain as it appears in the cosmos chain registry
 # example: name = "osmosis"
@@ -487,10 +861,21 @@ websocket = "wss://rpc1.unification.io/websocket"
 
 [messages]
 
-# Currency to display for the messages.
-# example: "usd" or "cad" or "eur"
+# Currency to display for the messages, or a list of currencies for chats with their own currency.
+# The first currency is the default, and the currency of the thresholds. Every currency CoinGecko quotes works
+# example: "usd" or "cad" or [ "usd", "eur" ]
 currency = "usd"
 
+# Show the IBC path of IBC tokens after their name, E.G. 1.00 ATOM via transfer/channel-0
+# The denom traces are cached in denom_traces.json, next to this config
+show-denom-path = false
+
+# What the amount filters do when the value of an amount is unknown, because none of its coins has a fresh price,
+# and none of the denom-thresholds of the message type apply
+# "block" filters the message, "pass" sends it, and "native" compares the amount of the chains' coin to the
+# native-threshold of the message type instead. Every message type can set its own unknown-price
+unknown-price = "block"
+
 [messages.transfers]
 # Enable or disable this message type entirely
 enable = true
@@ -501,12 +886,22 @@ enable = true
 filter = "default"
 
 # When filter is set to blacklist, Define a blacklist condition(s),
-# Conditions can be an address, memo, name, etc. If the string is recoginzed in the
-# message, it will prevent the message from sending.
+# Conditions can be an address, memo, name, etc. If a field of the message matches the condition,
+# it will prevent the message from sending.
 
 # Whitelist is the inverse of blacklist, this will ONLY allow messages that
-# contain an item defined in the list.
-list = [ "Delegate(rewards)", "Cosmostation" , "100100" ,"und1hdn830wndtquqxzaz3rds7r7hqgpsg5q9ggxpk" ]
+# match an item defined in the list.
+
+# An item on its own matches any field of the message containing it, like an address, a name, a memo or an amount.
+# Items can also target a field, with "field = value" for an exact match, "field ^= value" for a prefix,
+# and "field ~= expression" for a regular expression. Items starting with something other than a field,
+# like a memo containing =, are matched as text. Amounts and addresses on their own also match longer values
+# containing them, E.G. 100 matches 100100 and any hash containing 100, so use "amount = 100" instead
+# Every message has the fields type, action, hash, memo, denom, amount, sender, recipient, validator and delegator,
+# along with the fields of its template listed below. Addresses also match by their name, amounts by the amount
+# of the coin, E.G. 100100, or the coin, E.G. 100100000000000nund, and denoms by their denom or display name
+# example: list = [ "sender = und1hdn830wndtquqxzaz3rds7r7hqgpsg5q9ggxpk", "denom ^= ibc/", "memo ~= (?i)airdrop" ]
+list = [ "Delegate(rewards)", "Cosmostation" , "amount = 100100" ,"sender = und1hdn830wndtquqxzaz3rds7r7hqgpsg5q9ggxpk" ]
 
 # Enable or disable the amount filter, if this is set to true, will filter messages if the total
 # of the transaction falls below the threshold. Threshold should be set to units of the currency amount.
@@ -517,6 +912,79 @@ amount-filter = false
 # will be filtered, and will not send.
 threshold = 1000
 
+# Only takes affect if amount-filter = true and unknown-price = "native", for amounts without a known value.
+# The threshold in the chains' coin, E.G. with native-threshold = 50000 transfers of less than 50,000 FUND
+# are filtered. Every message type accepts one
+# unknown-price = "native"
+# native-threshold = 50000
+
+# Optional thresholds in coins instead of the currency, by the display name or base denom of the coin.
+# In "or" mode a threshold only applies to amounts with its coin, in "and" mode amounts without its coin fail it.
+# IBC tokens are counted by their origin, so ATOM counts the ATOM of every IBC channel. Every message type accepts them
+# example: denom-thresholds = { FUND = 50000, ATOM = 1000 }
+
+# How the threshold and the denom-thresholds are combined, "or" sends messages meeting any of them and "and"
+# only sends messages meeting all of them. Thresholds which can't be checked are skipped, like the threshold
+# of an amount without a known value. When none of them can be checked, unknown-price decides
+# threshold-mode = "or"
+
+# Optional color of the discord embeds of this message type, as a hex color. Every message type accepts one,
+# by default each type has its own color
+# example: discord-color = "#5865F2"
+
+# Optional Go text/template replacing the default layout of the message, every message type accepts one.
+# The fields of the message are available like {{ .sender }}, along with these helpers:
+# account (links an address), amount (formats an amount), txlink (links an amount to the TX),
+# hashlink (links the TX) and bold. The fields and helpers are escaped for each client,
+# use bold instead of markdown so the template works on every client. Text written in the template itself
+# isn't escaped, with telegram-parse-mode = "markdownv2" characters like . and - must be escaped with a backslash.
+# With a template, the memo is only shown if the template uses .memo. Using a field the message type doesn't have
+# stops reFUNDScan at startup, listing the fields the type has. Every type has .hash, and the types with a
+# message action also have .action and .memo
+# Fields per type:
+# transfers: sender, recipient, amount
+# multi-send, distributions: sender, count, total, recipients, a list with recipient, amount
+# ibc-transfers-in, ibc-transfers-out: route, sender, recipient, final_recipient, swap, contract, amount
+# ibc-transfers-failed: refunds, a list with sender, recipient, amount, reason, original
+# withdraw-rewards: delegator, total, validators, a list with validator, amount
+# withdraw-commission: validator, amount
+# delegations, undelegations: validator, delegator, amount
+# redelegations: source, destination, delegator, amount
+# restake: validator, total, delegators, a list with delegator, amount
+# register-account, register-domain, delete-account: starname, fee
+# transfer-account, transfer-domain: starname, sender, recipient, fee
+# renew-account, renew-domain: starname, expires, fee
+# replace-resources: starname, owner, added, removed, resources (the current addresses), fee
+#   added and removed are empty when the previous addresses could not be fetched
+# replace-metadata: starname, owner, metadata, fee
+# create-escrow, update-escrow, refund-escrow: starname, seller, price, expires
+# transfer-to-escrow: starname, seller, buyer, price, expires
+# burns: burner, amount, total
+# mints: minter, amount, total
+# supply-report: supply, change, burned, minted, burn_addresses, period
+# price-alerts: coin, alert (move, ath or volume), price, change, window, volume
+# other: name, module, signer, amounts, total
+# Custom message types have their roles as fields
+# example:
+# template = """
+#
+# {{ bold "📬 Transfer 📬" }}
+# {{ account .sender }} → {{ account .recipient }}
+# {{ txlink .hash .amount }}"""
+
+[messages.multi-send]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+[messages.distributions]
+# Only sent if [distributions] is enabled
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
 [messages.ibc-transfers-in]
 enable = false
 filter = "default"
@@ -529,6 +997,17 @@ filter = "default"
 list = []
 amount-filter = false
 threshold = 1000
+[messages.ibc-transfers-failed]
+# Announces IBC transfers out which timed out, or were rejected by the other chain, and refunded
+enable = false
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+
+# If the original IBC Out message was sent, edit it to show the transfer was refunded,
+# instead of sending a new message to that channel
+edit-original = true
 [messages.withdraw-rewards]
 enable = false
 filter = "default"
@@ -596,6 +1075,259 @@ filter = "default"
 list = []
 amount-filter = false
 threshold = 1000
+[messages.renew-account]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+[messages.renew-domain]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+[messages.replace-resources]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+[messages.replace-metadata]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+# Starname escrow marketplace
+[messages.create-escrow]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+[messages.update-escrow]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+[messages.transfer-to-escrow]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+[messages.refund-escrow]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+# Supply tracking, only sent if [supply] is enabled
+# Mints are the mints of TXs, the inflation minted at the start of each block isn't part of a TX. It is part
+# of the minted amount of the supply report instead, derived from the change of the supply
+[messages.burns]
+enable = true
+filter = "default"
+list = []
+amount-filter = true
+threshold = 1000
+[messages.mints]
+enable = true
+filter = "default"
+list = []
+amount-filter = true
+threshold = 1000
+[messages.supply-report]
+enable = true
+filter = "default"
+list = []
+amount-filter = false
+threshold = 1000
+[messages.price-alerts]
+# Only sent if [alerts.price] is enabled
+enable = true
+filter = "default"
+list = []
+# Any message action without its own message type, like actions of new modules after a chain upgrade.
+# The list of this type filters on the action names before the message is made, with the fields action, name and module
+# example: list = [ "action ^= /cosmos.gov", "MsgVote" ]
+[messages.other]
+enable = false
+filter = "blacklist"
+list = [ "/ibc.core.client.v1.MsgUpdateClient", "/mainchain.beacon.v1.MsgRecordBeaconTimestamp", "/mainchain.wrkchain.v1.MsgRecordWrkChainBlock" ]
+amount-filter = false
+threshold = 1000
+
+# Custom message types, for actions of modules without a built-in message type. Repeat the
+# [[messages.custom]] section for each type, they use the same filters as the other message types.
+# action: the message action of the type
+# required: event attributes the TX must have, otherwise the message isn't sent
+# roles: names mapped to event attributes, with an optional index of the value, E.G. transfer.amount[1]
+#   or wasm.sender[-1] for the last value. sender, recipient, validator, delegator and signer are shown
+#   as account links, amount is linked to the TX and used by the amount filter, and proposal is linked
+#   to the proposal on the explorer
+# template: optional, see [messages.transfers]. Without a template, the roles are listed under the title
+[[messages.custom]]
+name = "Vote"
+action = "/cosmos.gov.v1beta1.MsgVote"
+title = "Governance Vote"
+enable = false
+filter = "blacklist"
+list = []
+amount-filter = false
+threshold = 0
+required = [ "proposal_vote.proposal_id" ]
+roles = { voter = "message.sender", proposal = "proposal_vote.proposal_id", option = "proposal_vote.option" }
+template = """
+
+{{ bold "🗳️ Vote 🗳️" }}
+
+{{ bold "Proposal:" }} {{ .proposal }}
+{{ bold "Voter:" }} {{ account .voter }}
+{{ bold "Option:" }} {{ .option }}
+{{ bold "TX:" }} {{ hashlink .hash }}"""
+
+[supply]
+# Tracks burns and mints of the chains' coin, and periodically reports its total supply
+enable = false
+
+# Transfers to these addresses are counted as burns
+burn-addresses = [ "und1qqqqqqqqqqqqqqqqqqqqqqqqqqqqph4djz5txt" ]
+
+# Hours between each supply report, 0 disables the report
+report-interval = 24
+
+[alerts.price]
+# Announces moves of the price of the chains' coin, in the first currency of [messages]
+enable = false
+
+# Announce a new all-time high price
+ath = true
+
+# Announce when the 24h volume is at least this many times the 24h volume of a day ago, 0 disables it
+volume-spike = 3
+
+# Minutes before the same alert is sent again
+cooldown = 60
+
+# Announce moves of at least the percentage, up or down, within the window in minutes
+[[alerts.price.moves]]
+percent = 10
+window = 60
+
+[[alerts.price.moves]]
+percent = 20
+window = 1440
+
+[expressions]
+# Filter expressions, for rules the lists and thresholds of the message types can't express. Messages have to
+# match the filter expression, besides the filters of their type
+# Fields are compared with == and != against strings or numbers, =~ and !~ against regular expressions,
+# <, <=, > and >= against numbers, and with in against a list of the config or a list like [ "a", "b" ].
+# Comparisons are combined with &&, || and !, and grouped with parentheses. The fields are the fields of the
+# lists of the message types, and amount_<currency> for the value of the amount in a currency of [messages].
+# A field without a comparison checks if the message has the field. Fields with several values, like the
+# denoms of a multi send, match if any of their values match, != and !~ match if none do
+# Test an expression offline against the responses in debug/jsonoutputs with: reFUNDScan --config ~/.refundscan --test-filter '<expression>'
+# The test only knows the coin of [chaininfo], the names of [address] and the prices of the static and file price sources
+# example: filter = 'type == "Delegations" && (validator in watched || amount_usd > 5000) && !(memo =~ "spam")'
+filter = ""
+
+# Optional expression per chat, the chats only get the messages matching their expression. Other chats get every message
+# example: chats = { "@MyWhaleChannel" = 'amount_usd >= 100000', "1125944525457975326" = 'type in [ "Burns", "Mints" ]' }
+
+# Named lists used by the expressions with in
+[expressions.lists]
+# example: watched = [ "undvaloper1k03uvkkzmtkvfedufaxft75yqdfkfgvgm77zwm" ]
+
+[distributions]
+# Collapses many transfers from the same sender into one distribution message, like airdrops
+# Needs [messages.distributions] to be enabled as well
+# When enabled, every transfer message which passed its filters is delayed until the window has passed,
+# even if the sender makes no more transfers. Only transfers which passed the filters of [messages.transfers]
+# count towards min-transfers and are listed in the distribution message
+enable = false
+
+# Number of blocks to collect a senders' transfers for
+window = 5
+
+# Minimum number of transfers from a sender within the window, for them to be collapsed
+min-transfers = 5
+
+# Number of recipients to list in distribution and multi-send messages
+top-recipients = 5
+
+# Seconds to wait per block of the window before sending the held transfers, in case no more TXs arrive to
+# advance the height. Transfers are delayed by up to window * block-timeout seconds
+block-timeout = 10
+
+[explorer]
+# The block explorers linked in the messages, presets are "ping.pub" and "mintscan"
+# The first explorer is used for the links of the addresses and amounts. When more than one is used,
+# the messages link the TX on every explorer
+# example: use = [ "mintscan", "ping.pub" ]
+use = [ "ping.pub" ]
+
+# Other explorers, E.G. a self-hosted one, can be declared with URL templates and used by name
+# {chain} is replaced with the chains' path on the explorer, {hash} with a TX hash, {address} with an
+# account or validator address and {id} with a proposal ID. Only name and tx are required
+# [[explorer.custom]]
+# name = "My Explorer"
+# tx = "https://explorer.example.com/{chain}/tx/{hash}"
+# account = "https://explorer.example.com/{chain}/account/{address}"
+# validator = "https://explorer.example.com/{chain}/validator/{address}"
+# proposal = "https://explorer.example.com/{chain}/proposal/{id}"
+# home = "https://explorer.example.com/{chain}"
+
+# Overrides for a chain, keyed by its name in the chain registry
+# path: the chains' path on the explorers, by default its name in the chain registry
+# use: the explorers of the chain, by default the ones above. The first one links the chains' addresses
+# [explorer.chains.cosmoshub]
+# path = "cosmos"
+# use = [ "mintscan" ]
+
+[prices]
+# Where the prices of the coins come from, in order. The first source which knows the price of a coin is used
+# coingecko: CoinGecko, by the coin gecko id of the chain registry
+# osmosis: the price of an Osmosis pool, needs the pool of the asset below
+# static: the static price of the asset below
+# file: a local JSON file of prices keyed by denom and currency, E.G. { "nund": { "usd": 0.0123 } }
+# example: sources = [ "coingecko", "osmosis", "file" ]
+sources = [ "coingecko" ]
+
+# The JSON file for the file source, relative to this config. The file is read again when it changes
+# example: file = "prices.json"
+file = ""
+
+# Minutes after which a price is stale, 0 means prices never go stale. A stale price is only used when
+# none of the sources of the coin has a fresh price, and it counts as unknown for the amount filters
+max-age = 60
+
+# "mark" shows stale values as approximate with their age, E.G. (≈ 0.03 USD, 2h old)
+# "hide" shows stale values as unknown, E.G. (≈ ? USD)
+stale = "mark"
+
+[prices.osmosis]
+# Rest URL to query the Osmosis pools
+rest = "https://lcd.osmosis.zone/"
+
+# Use the average price over the past number of minutes instead of the spot price, 0 for the spot price
+twap = 0
+
+# Price settings of a coin, by its denom on the chain it was issued on, E.G. "uatom" for ATOM on any chain
+# sources: the sources of the coin in order, by default the ones above
+# static: the price of the coin per currency
+# osmosis-pool: the Osmosis pool to price the coin with, osmosis-denom is the coins' denom on Osmosis
+# The pool is quoted in USDC by default, other quote assets need osmosis-quote-denom,
+# osmosis-quote-exponent and osmosis-quote-coin-gecko-id
+# [[prices.assets]]
+# denom = "nund"
+# sources = [ "osmosis", "coingecko", "static" ]
+# static = { usd = 0.01, eur = 0.009 }
+# osmosis-pool = 1234
+# osmosis-denom = "ibc/<hash of the coin on osmosis>"
 
 [address]
 # Optionally define a list of wallets to be named when their account/val addresses
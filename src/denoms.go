@@ -0,0 +1,139 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "log"
+    "os"
+    "strings"
+    "sync"
+
+    "github.com/fatih/color"
+)
+
+// The path and base denom of an IBC token, E.G. transfer/channel-0 and uatom
+type DenomTrace struct {
+    Path      string `json:"path"`
+    BaseDenom string `json:"base_denom"`
+}
+
+// Denom traces keyed by the hash of the IBC denom. Traces never change, so they are kept in a file next
+// to the config and only queried once
+var denomTraces = struct {
+    sync.Mutex
+    m    map[string]DenomTrace
+    file string
+}{m: map[string]DenomTrace{}}
+
+// Loads the denom traces cached in the file, the file is created once a trace is queried
+func loadDenomTraces(file string) {
+    denomTraces.Lock()
+    defer denomTraces.Unlock()
+    denomTraces.file = file
+    data, err := os.ReadFile(file)
+    if errors.Is(err, os.ErrNotExist) {
+        return
+    }
+    if err == nil {
+        err = json.Unmarshal(data, &denomTraces.m)
+    }
+    if err != nil {
+        log.Println(color.YellowString("Failed to read the cached denom traces, they will be queried again: %v", err))
+        denomTraces.m = map[string]DenomTrace{}
+    }
+}
+
+// Returns the denom trace of the IBC denom hash, querying it if it isn't cached
+func getDenomTrace(hash string) (DenomTrace, error) {
+    denomTraces.Lock()
+    trace, ok := denomTraces.m[hash]
+    denomTraces.Unlock()
+    if ok {
+        return trace, nil
+    }
+    var res IBCResponse
+    if err := getData(config.Connections.Rest + "ibc/apps/transfer/v1/denom_traces/" + hash, &res); err != nil {
+        return DenomTrace{}, err
+    }
+    if res.DenomTrace.BaseDenom == "" {
+        return DenomTrace{}, errors.New("No denom trace available for: ibc/" + hash)
+    }
+    denomTraces.Lock()
+    defer denomTraces.Unlock()
+    denomTraces.m[hash] = res.DenomTrace
+    if denomTraces.file != "" {
+        data, _ := json.MarshalIndent(denomTraces.m, "", "  ")
+        if err := os.WriteFile(denomTraces.file, data, 0644); err != nil {
+            log.Println(color.YellowString("Failed to cache denom traces: %v", err))
+        }
+    }
+    return res.DenomTrace, nil
+}
+
+// Returns the chain the IBC token originates from, following the channels of its path from this chain
+// E.G. transfer/channel-0/transfer/channel-141 is the chain behind channel-141 of the chain behind channel-0
+// If the path can't be followed, falls back to the chain whose coin is the base denom, when only one chain has it
+func originChain(trace DenomTrace) (*ChainData, bool) {
+    hops := strings.Split(trace.Path, "/")
+    rest := config.Connections.Rest
+    var origin *ChainData
+    for i := 0; i + 1 < len(hops); i += 2 {
+        origin = nil
+        if rest == "" {
+            break
+        }
+        id, err := getChannelChainID(rest, hops[i], hops[i+1])
+        if err != nil {
+            break
+        }
+        for j := range config.OtherChains {
+            if config.OtherChains[j].ChainID == id {
+                origin = &config.OtherChains[j]
+            }
+        }
+        if origin == nil {
+            break
+        }
+        rest = origin.Rest
+    }
+    if origin != nil {
+        // The token isn't the coin of the chain it comes from, which isn't a known token
+        if origin.Denom != trace.BaseDenom {
+            return nil, false
+        }
+        return origin, true
+    }
+    // Chains can share a base denom, so it only identifies the chain when a single chain has it
+    var matches []*ChainData
+    for i := range config.OtherChains {
+        if config.OtherChains[i].Denom == trace.BaseDenom {
+            matches = append(matches, &config.OtherChains[i])
+        }
+    }
+    if len(matches) > 1 {
+        var names []string
+        for _, chain := range matches {
+            names = append(names, chain.ChainName)
+        }
+        log.Println(color.YellowString("Could not follow the path %s of %s, which is the coin of several chains: %s",
+            trace.Path, trace.BaseDenom, strings.Join(names, ", ")))
+        return nil, false
+    }
+    if len(matches) == 1 {
+        return matches[0], true
+    }
+    return nil, false
+}
+
+// Returns the denom trace of the IBC denom hash and the chain it originates from
+func getIBC(hash string) (DenomTrace, *ChainData, error) {
+    trace, err := getDenomTrace(hash)
+    if err != nil {
+        return trace, nil, err
+    }
+    chain, ok := originChain(trace)
+    if !ok {
+        return trace, nil, errors.New("No known chain has the base denom: " + trace.BaseDenom)
+    }
+    return trace, chain, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+    "testing"
+)
+
+func TestOriginChain(t *testing.T) {
+    channel := func(rest string, channel string) string {
+        return rest + "ibc/core/channel/v1/channels/" + channel + "/ports/transfer/client_state"
+    }
+    home := "https://rest.unification.io/"
+    osmosis := "https://rest.osmosis.zone/"
+    responses := map[string]string{
+        channel(home, "channel-0"):      clientState("osmosis-1"),
+        channel(home, "channel-3"):      clientState("unknown-1"),
+        channel(osmosis, "channel-141"): clientState("cosmoshub-4"),
+    }
+    tests := []struct {
+        name   string
+        trace  DenomTrace
+        // A chain with the same coin as the Cosmos Hub
+        shared bool
+        want   string
+    }{
+        {name: "direct", trace: DenomTrace{Path: "transfer/channel-0", BaseDenom: "uosmo"}, want: "osmosis"},
+        {name: "multi-hop", trace: DenomTrace{Path: "transfer/channel-0/transfer/channel-141", BaseDenom: "uatom"}, want: "cosmoshub"},
+        {name: "multi-hop with a shared base denom", trace: DenomTrace{Path: "transfer/channel-0/transfer/channel-141", BaseDenom: "uatom"}, shared: true, want: "cosmoshub"},
+        {name: "not the coin of the chain it comes from", trace: DenomTrace{Path: "transfer/channel-0", BaseDenom: "uatom"}},
+        {name: "unresolved channel falls back to the base denom", trace: DenomTrace{Path: "transfer/channel-7", BaseDenom: "uatom"}, want: "cosmoshub"},
+        {name: "unknown chain falls back to the base denom", trace: DenomTrace{Path: "transfer/channel-3", BaseDenom: "uosmo"}, want: "osmosis"},
+        {name: "unresolved channel with a shared base denom", trace: DenomTrace{Path: "transfer/channel-7", BaseDenom: "uatom"}, shared: true},
+        {name: "unresolved channel with an unknown base denom", trace: DenomTrace{Path: "transfer/channel-7", BaseDenom: "ufoo"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            useTestChains(t)
+            useTestResponses(t, responses)
+            if tt.shared {
+                config.OtherChains = append(config.OtherChains, ChainData{ChainName: "cosmoshub-fork", ChainID: "fork-1",
+                    DisplayName: "ATOM", Denom: "uatom", Exponent: 6})
+            }
+            chain, ok := originChain(tt.trace)
+            if tt.want == "" {
+                if ok {
+                    t.Errorf("originChain(%v) = %s, want no chain", tt.trace, chain.ChainName)
+                }
+                return
+            }
+            if !ok {
+                t.Fatalf("originChain(%v) found no chain, want %s", tt.trace, tt.want)
+            }
+            if chain.ChainName != tt.want {
+                t.Errorf("originChain(%v) = %s, want %s", tt.trace, chain.ChainName, tt.want)
+            }
+        })
+    }
+}
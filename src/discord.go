@@ -0,0 +1,280 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+    "unicode/utf8"
+
+    discord "github.com/bwmarrin/discordgo"
+    "github.com/fatih/color"
+)
+
+// Embed colors of the message types, unless set with discord-color in the config
+// Custom message types without a color use the default
+var discordColors = map[string]int{
+    "Transfer":         5793266,
+    "MultiSend":        3447003,
+    "Distributions":    3447003,
+    "IBCOut":           10181046,
+    "IBCIn":            10181046,
+    "IBCFailed":        15548997,
+    "Rewards":          15844367,
+    "Commission":       15844367,
+    "Delegations":      5763719,
+    "Undelegations":    15105570,
+    "Redelegations":    3066993,
+    "Restake":          1752220,
+    "RegisterAccount":  16705372,
+    "RegisterDomain":   16705372,
+    "TransferAccount":  16705372,
+    "TransferDomain":   16705372,
+    "DeleteAccount":    16705372,
+    "RenewAccount":     16705372,
+    "RenewDomain":      16705372,
+    "ReplaceResources": 16705372,
+    "ReplaceMetadata":  16705372,
+    "CreateEscrow":     16705372,
+    "UpdateEscrow":     16705372,
+    "TransferToEscrow": 16705372,
+    "RefundEscrow":     16705372,
+    "Burns":            15105570,
+    "Mints":            15844367,
+    "SupplyReport":     3447003,
+    "PriceAlerts":      15844367,
+    "Other":            9807270,
+}
+
+// Embed color of messages edited after their transfer was refunded
+const discordEditColor = 15548997
+
+// Avatar URLs of the Keybase identities, keyed by the identity. Identities without an avatar, or whose
+// lookup failed, are kept as well, so they aren't looked up again
+var avatars = struct {
+    sync.Mutex
+    m map[string]string
+}{m: map[string]string{}}
+
+// Keybase identities of the validators, keyed by their operator and account addresses, rebuilt when the validator
+// set is refreshed
+var identities = struct {
+    sync.Mutex
+    m map[string]string
+}{m: map[string]string{}}
+
+// Rebuilds the identities of the validators from the validator set
+func indexValidators() {
+    m := map[string]string{}
+    for _, val := range vals.Validators {
+        if val.Description.Identity == "" {
+            continue
+        }
+        m[val.OperatorAddress] = val.Description.Identity
+        if addr := valAccountAddr(val.OperatorAddress); addr != "" {
+            m[addr] = val.Description.Identity
+        }
+    }
+    identities.Lock()
+    identities.m = m
+    identities.Unlock()
+}
+
+// Returns the embed color of the message type
+func discordColor(typeName string, cfg MessageConfig) int {
+    if cfg.Color != "" {
+        if c, err := parseColor(cfg.Color); err == nil {
+            return c
+        }
+    }
+    if c, ok := discordColors[typeName]; ok {
+        return c
+    }
+    return 5793266
+}
+
+// Parses a hex color, E.G. #5865F2
+func parseColor(hex string) (int, error) {
+    c, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 24)
+    return int(c), err
+}
+
+// Limits of discord embeds, in characters
+const (
+    discordFieldLimit       = 1024
+    discordDescriptionLimit = 4096
+    discordEmbedLimit       = 6000
+    discordFieldsLimit      = 25
+)
+
+// A field of an embed, with the number of lines of the document field it shows
+type discordChunk struct {
+    field *discord.MessageEmbedField
+    lines int
+}
+
+// Renders the document as a discord embed, a field of the embed per field of the document, with the edit appended
+// Messages with a template are rendered in the description of the embed instead
+// The footer has the block height and the TX, and the thumbnail is the avatar of the validator of the message
+// Fields beyond the limits of discord are left out, ending with a field counting the lines left out
+func discordEmbed(doc Document, edit []Field) (*discord.MessageEmbed, error) {
+    m := discordMarkdown{}
+    embd := &discord.MessageEmbed{
+        Timestamp: fmt.Sprint(time.Now().Format(time.RFC3339)),
+    }
+    var chunks, editChunks []discordChunk
+    if doc.Template != nil {
+        text, err := doc.Render(m)
+        if err != nil {
+            return nil, err
+        }
+        embd.Description = truncateText(text, discordDescriptionLimit)
+    } else {
+        embd.Title = doc.Title
+        embd.Description = m.Escape(doc.Subtitle)
+        for _, field := range fieldsInCurrency(doc.Fields, doc.Currency) {
+            chunks = append(chunks, discordFields(m, field)...)
+        }
+        if doc.Memo != "" {
+            chunks = append(chunks, discordFields(m, Field{Name: "Memo", Lines: [][]Text{{plain(doc.Memo)}}})...)
+            chunks[len(chunks)-1].field.Inline = false
+        }
+    }
+    for _, field := range fieldsInCurrency(edit, doc.Currency) {
+        editChunks = append(editChunks, discordFields(m, field)...)
+    }
+    var footer []string
+    if doc.Height != "" {
+        footer = append(footer, "Height " + doc.Height)
+    }
+    if doc.Hash != "" {
+        footer = append(footer, "TX " + doc.Hash)
+    }
+    if len(doc.PriceSources) > 0 {
+        footer = append(footer, "Prices " + strings.Join(doc.PriceSources, ", "))
+    }
+    if len(footer) > 0 {
+        embd.Footer = &discord.MessageEmbedFooter{Text: strings.Join(footer, " • ")}
+    }
+    // The edit is kept whole, the fields of the document get the room left
+    budget := discordEmbedLimit - len(embd.Title) - len(embd.Description) - len(strings.Join(footer, " • "))
+    for _, chunk := range editChunks {
+        budget -= len(chunk.field.Name) + len(chunk.field.Value)
+    }
+    embd.Fields = limitFields(chunks, discordFieldsLimit - len(editChunks), budget)
+    for _, chunk := range editChunks {
+        embd.Fields = append(embd.Fields, chunk.field)
+    }
+    if avatar := getValidatorAvatar(doc.Validator); avatar != "" {
+        embd.Thumbnail = &discord.MessageEmbedThumbnail{URL: avatar}
+    }
+    return embd, nil
+}
+
+// Renders a field of a document as fields of a discord embed, which can't be empty or longer than the field limit
+// Long fields continue in fields without a name. Fields with a single line are shown inline, next to each other
+func discordFields(m Markup, field Field) []discordChunk {
+    var chunks []discordChunk
+    var value []string
+    flush := func() {
+        name := field.Name
+        if len(chunks) > 0 {
+            name = "\u200b"
+        }
+        text := strings.Join(value, "\n")
+        if text == "" {
+            text = "\u200b"
+        }
+        chunks = append(chunks, discordChunk{
+            field: &discord.MessageEmbedField{Name: name, Value: text, Inline: len(field.Lines) == 1},
+            lines: len(value),
+        })
+        value = nil
+    }
+    for _, line := range field.Lines {
+        text := truncateText(renderLine(m, line), discordFieldLimit)
+        if len(value) > 0 && len(strings.Join(value, "\n")) + 1 + len(text) > discordFieldLimit {
+            flush()
+        }
+        value = append(value, text)
+    }
+    if len(value) > 0 || len(chunks) == 0 {
+        flush()
+    }
+    return chunks
+}
+
+// Returns the fields fitting in the number of fields and characters left in the embed. When they don't all fit,
+// the last field counts the lines left out, E.G. …and 12 more
+func limitFields(chunks []discordChunk, slots int, budget int) []*discord.MessageEmbedField {
+    // Room kept for the field counting the lines left out
+    const note = 32
+    var fields []*discord.MessageEmbedField
+    for i, chunk := range chunks {
+        size := len(chunk.field.Name) + len(chunk.field.Value)
+        if i == len(chunks) - 1 && len(fields) < slots && size <= budget {
+            return append(fields, chunk.field)
+        }
+        if len(fields) < slots - 1 && size <= budget - note {
+            fields = append(fields, chunk.field)
+            budget -= size
+            continue
+        }
+        if slots < 1 || budget < note {
+            return fields
+        }
+        more := 0
+        for _, rest := range chunks[i:] {
+            more += rest.lines
+        }
+        return append(fields, &discord.MessageEmbedField{Name: "\u200b", Value: fmt.Sprintf("…and %d more", more)})
+    }
+    return fields
+}
+
+// Cuts the text to the limit in bytes, which is never more characters than the limit, ending it with …
+func truncateText(text string, limit int) string {
+    if len(text) <= limit {
+        return text
+    }
+    cut := limit - len("…")
+    for cut > 0 && !utf8.RuneStart(text[cut]) {
+        cut--
+    }
+    return text[:cut] + "…"
+}
+
+// Returns the avatar URL of a validator, from the Keybase identity it set. The address can be the
+// validators' operator or account address. Returns an empty string if the validator has no avatar
+func getValidatorAvatar(addr string) string {
+    if addr == "" {
+        return ""
+    }
+    identities.Lock()
+    identity := identities.m[addr]
+    identities.Unlock()
+    if identity == "" {
+        return ""
+    }
+    avatars.Lock()
+    url, ok := avatars.m[identity]
+    avatars.Unlock()
+    if ok {
+        return url
+    }
+    // The lookup is done without the lock, so a slow lookup doesn't hold up the other messages
+    var keybase KeybaseResponse
+    err := getData("https://keybase.io/_/api/1.0/user/lookup.json?fields=pictures&key_suffix=" + identity, &keybase)
+    if err != nil {
+        log.Println(color.YellowString("Failed to get Keybase avatar: %v", err))
+    } else if len(keybase.Them) > 0 {
+        url = keybase.Them[0].Pictures.Primary.URL
+    }
+    // Failed lookups are kept as well, so they aren't retried for every message
+    avatars.Lock()
+    avatars.m[identity] = url
+    avatars.Unlock()
+    return url
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+    "unicode/utf8"
+
+    discord "github.com/bwmarrin/discordgo"
+)
+
+func TestTruncateText(t *testing.T) {
+    tests := []struct {
+        text  string
+        limit int
+        want  string
+    }{
+        {"hello", 10, "hello"},
+        {"hello", 5, "hello"},
+        {"hello world", 8, "hello…"},
+        {"hello world", 3, "…"},
+        // Multi-byte characters aren't cut in half
+        {"ééééé", 6, "é…"},
+        {"ééééé", 7, "éé…"},
+        {"ééééé", 10, "ééééé"},
+    }
+    for _, tt := range tests {
+        got := truncateText(tt.text, tt.limit)
+        if got != tt.want {
+            t.Errorf("truncateText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+        }
+        if len(got) > tt.limit || !utf8.ValidString(got) {
+            t.Errorf("truncateText(%q, %d) = %q, longer than the limit or invalid", tt.text, tt.limit, got)
+        }
+    }
+}
+
+// Returns a field with the number of lines, each line the given number of characters
+func testField(name string, lines int, size int) Field {
+    field := Field{Name: name}
+    for i := 0; i < lines; i++ {
+        field.Lines = append(field.Lines, []Text{plain(strings.Repeat("a", size))})
+    }
+    return field
+}
+
+func TestDiscordFields(t *testing.T) {
+    tests := []struct {
+        name   string
+        field  Field
+        chunks int
+        inline bool
+    }{
+        {name: "single line", field: testField("Sender", 1, 10), chunks: 1, inline: true},
+        {name: "empty field", field: Field{Name: "Empty"}, chunks: 1},
+        {name: "a few lines", field: testField("Recipients", 5, 10), chunks: 1},
+        {name: "lines beyond the field limit", field: testField("Recipients", 100, 30), chunks: 4},
+        {name: "a line beyond the field limit", field: testField("Memo", 1, 3000), chunks: 1, inline: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            chunks := discordFields(discordMarkdown{}, tt.field)
+            if len(chunks) != tt.chunks {
+                t.Fatalf("got %d fields, want %d", len(chunks), tt.chunks)
+            }
+            lines := 0
+            for i, chunk := range chunks {
+                lines += chunk.lines
+                if chunk.field.Value == "" || len(chunk.field.Value) > discordFieldLimit {
+                    t.Errorf("field %d has %d characters", i, len(chunk.field.Value))
+                }
+                if chunk.field.Inline != tt.inline {
+                    t.Errorf("field %d inline = %v, want %v", i, chunk.field.Inline, tt.inline)
+                }
+                name := tt.field.Name
+                if i > 0 {
+                    name = "\u200b"
+                }
+                if chunk.field.Name != name {
+                    t.Errorf("field %d name = %q, want %q", i, chunk.field.Name, name)
+                }
+            }
+            if lines != len(tt.field.Lines) {
+                t.Errorf("fields show %d lines, want %d", lines, len(tt.field.Lines))
+            }
+        })
+    }
+}
+
+// Returns chunks of a line each, with a name and value of the given size together
+func testChunks(count int, size int) []discordChunk {
+    var chunks []discordChunk
+    for i := 0; i < count; i++ {
+        chunks = append(chunks, discordChunk{
+            field: &discord.MessageEmbedField{Name: "a", Value: strings.Repeat("b", size - 1)},
+            lines: 1,
+        })
+    }
+    return chunks
+}
+
+func TestLimitFields(t *testing.T) {
+    tests := []struct {
+        name   string
+        chunks []discordChunk
+        slots  int
+        budget int
+        fields int
+        more   string
+    }{
+        {name: "everything fits", chunks: testChunks(3, 10), slots: 25, budget: 6000, fields: 3},
+        {name: "no fields", slots: 25, budget: 6000},
+        {name: "too many fields", chunks: testChunks(30, 10), slots: 25, budget: 6000, fields: 25, more: "…and 6 more"},
+        {name: "exactly the number of slots", chunks: testChunks(25, 10), slots: 25, budget: 6000, fields: 25},
+        {name: "too many characters", chunks: testChunks(5, 100), slots: 25, budget: 350, fields: 4, more: "…and 2 more"},
+        {name: "the last field fits without the note", chunks: testChunks(2, 100), slots: 25, budget: 200, fields: 2},
+        {name: "no slots", chunks: testChunks(3, 10), slots: 0, budget: 6000},
+        {name: "no room for the note", chunks: testChunks(3, 100), slots: 25, budget: 20},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            fields := limitFields(tt.chunks, tt.slots, tt.budget)
+            if len(fields) != tt.fields {
+                t.Fatalf("got %d fields, want %d", len(fields), tt.fields)
+            }
+            size := 0
+            for _, field := range fields {
+                size += len(field.Name) + len(field.Value)
+            }
+            if size > tt.budget {
+                t.Errorf("fields have %d characters, more than the budget of %d", size, tt.budget)
+            }
+            if tt.more != "" && fields[len(fields)-1].Value != tt.more {
+                t.Errorf("last field = %q, want %q", fields[len(fields)-1].Value, tt.more)
+            }
+        })
+    }
+}
+
+func TestDiscordEmbedLimits(t *testing.T) {
+    var doc Document
+    doc.Title = "📦 Multi Send 📦"
+    doc.Hash = strings.Repeat("A", 64)
+    doc.Height = "1000"
+    for i := 0; i < 40; i++ {
+        doc.Fields = append(doc.Fields, testField(fmt.Sprintf("Field %d", i), 20, 40))
+    }
+    edit := []Field{testField("❌ Refunded", 1, 50), testField("Refund TX", 1, 64)}
+    embd, err := discordEmbed(doc, edit)
+    if err != nil {
+        t.Fatal(err)
+    }
+    size := len(embd.Title) + len(embd.Description) + len(embd.Footer.Text)
+    for _, field := range embd.Fields {
+        size += len(field.Name) + len(field.Value)
+        if len(field.Value) > discordFieldLimit {
+            t.Errorf("field %q has %d characters", field.Name, len(field.Value))
+        }
+    }
+    if size > discordEmbedLimit {
+        t.Errorf("embed has %d characters", size)
+    }
+    if len(embd.Fields) > discordFieldsLimit {
+        t.Errorf("embed has %d fields", len(embd.Fields))
+    }
+    // The edit is kept whole at the end
+    last := embd.Fields[len(embd.Fields)-2:]
+    if last[0].Name != "❌ Refunded" || last[1].Name != "Refund TX" {
+        t.Errorf("the edit fields are %q and %q", last[0].Name, last[1].Name)
+    }
+    if !strings.HasPrefix(embd.Fields[len(embd.Fields)-3].Value, "…and ") {
+        t.Errorf("the fields left out aren't counted, got %q", embd.Fields[len(embd.Fields)-3].Value)
+    }
+}
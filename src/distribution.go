@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The transfers from a single sender, collected over the block window
+type distribution struct {
+    Sender     string
+    Start      int
+    Hash       string
+    Recipients []string
+    Amounts    []string
+    // Transfer messages which passed their filters, sent if the transfers aren't collapsed or the distribution
+    // message is filtered
+    Held       []MessageResponse
+    resp       chan MessageResponse
+    timer      *time.Timer
+}
+
+// Open distributions, keyed by the sender
+var distributions = struct {
+    sync.Mutex
+    m map[string]*distribution
+}{m: map[string]*distribution{}}
+
+// Adds a transfer which passed its filters to the senders' distribution, opening one if needed, and holds
+// its message until the distribution is flushed
+// Returns false if the message isn't a held transfer, and should be sent now
+func holdTransfer(resp chan MessageResponse, msg MessageResponse, events Events) bool {
+    if !config.Config.DistributionsConfig.Enabled || msg.TypeName != "Transfer" || len(events.TxHeight) < 1 {
+        return false
+    }
+    h, err := strconv.Atoi(events.TxHeight[0])
+    if err != nil {
+        return false
+    }
+    sender := events.TransferSender[0]
+    distributions.Lock()
+    defer distributions.Unlock()
+    dist, ok := distributions.m[sender]
+    if !ok {
+        dist = &distribution{Sender: sender, Start: h, resp: resp}
+        // Fallback to flush the distribution, in case no more TXs arrive to advance the height
+        blockTimeout := time.Duration(config.Config.DistributionsConfig.BlockTimeout) * time.Second
+        window := time.Duration(config.Config.DistributionsConfig.Window) * blockTimeout
+        dist.timer = time.AfterFunc(window, func() {
+            distributions.Lock()
+            defer distributions.Unlock()
+            if distributions.m[sender] == dist {
+                delete(distributions.m, sender)
+                go flushDistribution(dist)
+            }
+        })
+        distributions.m[sender] = dist
+    }
+    dist.Recipients = append(dist.Recipients, events.TransferRecipient[1])
+    dist.Amounts = append(dist.Amounts, events.TransferAmount[1])
+    dist.Hash = events.TxHash[0]
+    dist.Held = append(dist.Held, msg)
+    return true
+}
+
+// Flushes the distributions whose block window has passed at the given height
+func observeHeight(height string) {
+    h, err := strconv.Atoi(height)
+    if err != nil {
+        return
+    }
+    distributions.Lock()
+    defer distributions.Unlock()
+    for sender, dist := range distributions.m {
+        if h >= dist.Start + config.Config.DistributionsConfig.Window {
+            dist.timer.Stop()
+            delete(distributions.m, sender)
+            go flushDistribution(dist)
+        }
+    }
+}
+
+// Sends one distribution message if the sender made enough transfers, otherwise, or if the distribution
+// message is filtered, sends the held transfer messages
+func flushDistribution(dist *distribution) {
+    if !sendDistribution(dist) {
+        for _, msg := range dist.Held {
+            dist.resp <- msg
+        }
+    }
+}
+
+// Sends the distribution message, returns false if the transfers aren't collapsed or the message was filtered
+func sendDistribution(dist *distribution) bool {
+    if len(dist.Recipients) < config.Config.DistributionsConfig.MinTransfers {
+        return false
+    }
+    var msg MessageResponse
+    msg.Type = config.Config.MessagesConfig.Distributions
+    msg.TypeName = "Distributions"
+    msg.Doc.Title = "📦 Distribution 📦"
+    msg.Doc.Hash = dist.Hash
+    total := mkRecipientSummary(&msg, dist.Sender, dist.Recipients, dist.Amounts, dist.Hash)
+    msg.Data["hash"] = dist.Hash
+    msg.Amount = total
+    if !msg.Type.Enabled || !isAllowedAmount(msg) || !renderMessage(&msg) || !isAllowedMessage(msg) {
+        return false
+    }
+    dist.resp <- msg
+    return true
+}
+
+// Summarizes transfers from one sender to many recipients into the message, listing the top recipients
+// Returns the total amount
+func mkRecipientSummary(msg *MessageResponse, sender string, recipients []string, amounts []string, hash string) string {
+    // Sum the amounts per recipient, amounts can be lists of coins
+    totalers := map[string]func(string) string{}
+    totals := map[string]string{}
+    var order []string
+    var total string
+    totaler := denomTotaler()
+    for i, recipient := range recipients {
+        if _, ok := totalers[recipient]; !ok {
+            order = append(order, recipient)
+            totalers[recipient] = denomTotaler()
+        }
+        totals[recipient] = totalers[recipient](amounts[i])
+        total = totaler(amounts[i])
+    }
+    // Largest value first, amounts of the same value, E.G. without a known price, by their first coin
+    values := map[string]*big.Rat{}
+    for _, recipient := range order {
+        values[recipient], _, _ = amountValue(totals[recipient], "")
+    }
+    sort.SliceStable(order, func(i, j int) bool {
+        if c := values[order[i]].Cmp(values[order[j]]); c != 0 {
+            return c > 0
+        }
+        a, _ := splitAmountDenom(totals[order[i]])
+        b, _ := splitAmountDenom(totals[order[j]])
+        return a.Cmp(b) > 0
+    })
+    var lines [][]Text
+    var top []map[string]string
+    for i, recipient := range order {
+        if i >= config.Config.DistributionsConfig.TopRecipients {
+            lines = append(lines, []Text{plain(fmt.Sprintf("...and %d more", len(order) - i))})
+            break
+        }
+        amount := totals[recipient]
+        lines = append(lines, []Text{accountText(recipient)}, []Text{coinsText(amount)})
+        top = append(top, map[string]string{"recipient": recipient, "amount": amount})
+    }
+    msg.Doc.Add("Sender", accountText(sender))
+    msg.Doc.Add("Recipients", plain(fmt.Sprintf("%d recipients, total ", len(order))), coinsText(total))
+    msg.Doc.AddList("Top Recipients", lines)
+    msg.Doc.AddGroup("Total", amountText(hash, total))
+    msg.Data = MessageData{
+        "sender": sender,
+        "count": strconv.Itoa(len(order)),
+        "recipients": top,
+        "total": total,
+    }
+    return total
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+    "bytes"
+    "html"
+    "strings"
+    "text/template"
+)
+
+// A piece of text, which is a link if it has a URL
+type Text struct {
+    Text  string
+    URL   string
+    // Coins shown as their formatted amount in the currency of the document, instead of the text
+    Coins string
+    // The address an account name stands for, so the filters can match the name
+    Account string
+}
+
+// A labelled value of a message, with one or more lines of text
+type Field struct {
+    Name  string
+    Lines [][]Text
+    // Separates the field from the previous ones with an empty line
+    Break bool
+}
+
+// A message as a structured document, rendered separately for each client
+type Document struct {
+    Title     string
+    Subtitle  string
+    Fields    []Field
+    Memo      string
+    // The TX the message is about
+    Hash      string
+    Height    string
+    // The validator the message is about, its avatar is shown by the clients that support it
+    Validator string
+    // The sources of the prices of the messages' amount
+    PriceSources []string
+    // The currency the amounts are shown in, the default currency if empty
+    Currency  string
+    // The message types' template and the fields it renders, replaces the document if set
+    Template  *template.Template
+    Data      MessageData
+}
+
+// Adds a field with a single line
+func (doc *Document) Add(name string, value ...Text) {
+    doc.Fields = append(doc.Fields, Field{Name: name, Lines: [][]Text{value}})
+}
+
+// Adds a field with a single line, starting a new group of fields
+func (doc *Document) AddGroup(name string, value ...Text) {
+    doc.Fields = append(doc.Fields, Field{Name: name, Lines: [][]Text{value}, Break: true})
+}
+
+// Adds a field with a line per value, starting a new group of fields
+func (doc *Document) AddList(name string, lines [][]Text) {
+    doc.Fields = append(doc.Fields, Field{Name: name, Lines: lines, Break: true})
+}
+
+// Plain text
+func plain(text string) Text {
+    return Text{Text: text}
+}
+
+// Returns the text without its links, with amounts in the default currency
+func plainString(texts []Text) string {
+    var str string
+    for _, t := range texts {
+        str += t.inCurrency("").Text
+    }
+    return str
+}
+
+// Returns the text with its coins formatted in the currency
+func (t Text) inCurrency(currency string) Text {
+    if t.Coins != "" {
+        t.Text = denomToAmount(t.Coins, currency)
+        t.Coins = ""
+    }
+    return t
+}
+
+// Returns a copy of the fields with their coins formatted in the currency
+func fieldsInCurrency(fields []Field, currency string) []Field {
+    var formatted []Field
+    for _, field := range fields {
+        var lines [][]Text
+        for _, line := range field.Lines {
+            var texts []Text
+            for _, t := range line {
+                texts = append(texts, t.inCurrency(currency))
+            }
+            lines = append(lines, texts)
+        }
+        field.Lines = lines
+        formatted = append(formatted, field)
+    }
+    return formatted
+}
+
+// Formats text for a client, escaping the characters the client would interpret
+type Markup interface {
+    Escape(text string) string
+    // Text given to Bold and Link is already escaped
+    Bold(text string) string
+    Link(text string, url string) string
+}
+
+// Markdown used to filter and log the messages, nothing is escaped so the lists can match any text
+type markdown struct{}
+
+func (markdown) Escape(text string) string { return text }
+func (markdown) Bold(text string) string { return "**" + text + "**" }
+func (markdown) Link(text string, url string) string { return "[" + text + "](" + url + ")" }
+
+// Telegram HTML parse mode
+type telegramHTML struct{}
+
+func (telegramHTML) Escape(text string) string { return html.EscapeString(text) }
+func (telegramHTML) Bold(text string) string { return "<b>" + text + "</b>" }
+func (telegramHTML) Link(text string, url string) string {
+    return `<a href="` + html.EscapeString(url) + `">` + text + "</a>"
+}
+
+// Telegram MarkdownV2 parse mode
+type telegramMarkdownV2 struct{}
+
+var markdownV2Escaper = strings.NewReplacer(
+    `\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+    ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`)
+var markdownV2URLEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+func (telegramMarkdownV2) Escape(text string) string { return markdownV2Escaper.Replace(text) }
+func (telegramMarkdownV2) Bold(text string) string { return "*" + text + "*" }
+func (telegramMarkdownV2) Link(text string, url string) string {
+    return "[" + text + "](" + markdownV2URLEscaper.Replace(url) + ")"
+}
+
+// Discord markdown, used in embeds
+type discordMarkdown struct{}
+
+var discordEscaper = strings.NewReplacer(
+    `\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`, ">", `\>`, "[", `\[`, "]", `\]`)
+
+func (discordMarkdown) Escape(text string) string { return discordEscaper.Replace(text) }
+func (discordMarkdown) Bold(text string) string { return "**" + text + "**" }
+func (discordMarkdown) Link(text string, url string) string { return "**[" + text + "](" + url + ")**" }
+
+// Renders a piece of text, coins which weren't formatted yet are shown in the default currency
+func renderText(m Markup, t Text) string {
+    t = t.inCurrency("")
+    if t.URL == "" {
+        return m.Escape(t.Text)
+    }
+    return m.Link(m.Escape(t.Text), t.URL)
+}
+
+// Renders a line of texts
+func renderLine(m Markup, line []Text) string {
+    var str string
+    for _, t := range line {
+        str += renderText(m, t)
+    }
+    return str
+}
+
+// Renders the value of a field, a line per line of the field
+func renderLines(m Markup, lines [][]Text) string {
+    var rendered []string
+    for _, line := range lines {
+        rendered = append(rendered, renderLine(m, line))
+    }
+    return strings.Join(rendered, "\n")
+}
+
+// Renders the document as text in its currency, with the title and fields in bold
+// Returns an error if the template of the document failed to render
+func (doc Document) Render(m Markup) (string, error) {
+    if doc.Template != nil {
+        return doc.renderTemplate(m)
+    }
+    str := m.Bold(m.Escape(doc.Title)) + "\n"
+    if doc.Subtitle != "" {
+        str += "\n" + m.Escape(doc.Subtitle) + "\n"
+    }
+    str += renderFields(m, fieldsInCurrency(doc.Fields, doc.Currency))
+    if doc.Memo != "" {
+        str += "\n" + m.Bold(m.Escape("Memo:")) + " " + m.Escape(doc.Memo)
+    }
+    return str, nil
+}
+
+// Renders fields as a line per field, or a line per value for fields with multiple lines
+// The first field doesn't start a new group, the text before it ends with an empty line
+func renderFields(m Markup, fields []Field) string {
+    var str string
+    for i, field := range fields {
+        if field.Break && i > 0 {
+            str += "\n"
+        }
+        str += "\n" + m.Bold(m.Escape(field.Name + ":"))
+        if len(field.Lines) == 1 {
+            str += " " + renderLine(m, field.Lines[0])
+        } else {
+            str += "\n" + renderLines(m, field.Lines)
+        }
+    }
+    return str
+}
+
+// Executes the template of the document with the helpers of the markup
+func (doc Document) renderTemplate(m Markup) (string, error) {
+    tmpl, err := doc.Template.Clone()
+    if err != nil {
+        return "", err
+    }
+    var buf bytes.Buffer
+    if err := tmpl.Funcs(templateFuncs(m, doc.Currency)).Execute(&buf, doc.Data); err != nil {
+        return "", err
+    }
+    return buf.String(), nil
+}
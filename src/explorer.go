@@ -0,0 +1,136 @@
+package main
+
+import (
+    "log"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+// A block explorer, with URL templates for its pages
+// {chain} is replaced with the chains' path on the explorer, and {hash}, {address} or {id} with what is linked
+type Explorer struct {
+    Name      string `toml:"name"`
+    TX        string `toml:"tx"`
+    Account   string `toml:"account"`
+    Validator string `toml:"validator"`
+    Proposal  string `toml:"proposal"`
+    Home      string `toml:"home"`
+}
+
+// Explorers that can be used by name without declaring them
+var explorerPresets = map[string]Explorer{
+    "ping.pub": {
+        Name:      "ping.pub",
+        TX:        "https://ping.pub/{chain}/tx/{hash}",
+        Account:   "https://ping.pub/{chain}/account/{address}",
+        Validator: "https://ping.pub/{chain}/staking/{address}",
+        Proposal:  "https://ping.pub/{chain}/gov/{id}",
+        Home:      "https://ping.pub/{chain}",
+    },
+    "mintscan": {
+        Name:      "Mintscan",
+        TX:        "https://www.mintscan.io/{chain}/tx/{hash}",
+        Account:   "https://www.mintscan.io/{chain}/address/{address}",
+        Validator: "https://www.mintscan.io/{chain}/validators/{address}",
+        Proposal:  "https://www.mintscan.io/{chain}/proposals/{id}",
+        Home:      "https://www.mintscan.io/{chain}",
+    },
+}
+
+// Paths of the chains whose path on the explorers isn't their name in the chain registry
+// Chains can be added or replaced with [explorer.chains] in the config
+var explorerPaths = map[string]string{
+    "cosmoshub": "cosmos",
+}
+
+// Returns the explorers with the given names, presets or declared with [[explorer.custom]]
+// Unknown names stop reFUNDScan, defaults to ping.pub if no names are given
+func resolveExplorers(names []string, custom []Explorer) []Explorer {
+    if len(names) == 0 {
+        names = []string{"ping.pub"}
+    }
+    var explorers []Explorer
+    for _, name := range names {
+        found := false
+        for _, explorer := range custom {
+            if explorer.Name == name {
+                explorers = append(explorers, explorer)
+                found = true
+                break
+            }
+        }
+        if preset, ok := explorerPresets[strings.ToLower(name)]; ok && !found {
+            explorers = append(explorers, preset)
+            found = true
+        }
+        if !found {
+            log.Fatal(color.RedString("Unknown explorer " + name + ", use ping.pub, mintscan, or declare it with [[explorer.custom]]"))
+        }
+    }
+    return explorers
+}
+
+// Returns the path of the chain on the explorers, E.G. cosmoshub becomes cosmos
+func explorerPath(chainName string) string {
+    if path, ok := config.Config.ExplorerConfig.Chains[chainName]; ok && path.Path != "" {
+        return path.Path
+    }
+    if path, ok := explorerPaths[chainName]; ok {
+        return path
+    }
+    return chainName
+}
+
+// Fills the URL template of an explorer, returns an empty string if the explorer has no such page
+func (explorer Explorer) url(tmpl string, chain ChainData, key string, value string) string {
+    if tmpl == "" {
+        return ""
+    }
+    return strings.NewReplacer("{chain}", chain.ExplorerPath, "{"+key+"}", value).Replace(tmpl)
+}
+
+// Returns the URL of a transaction on the home chain, on the first explorer
+func txURL(hash string) string {
+    explorer := config.Chain.Explorers[0]
+    return explorer.url(explorer.TX, config.Chain, "hash", hash)
+}
+
+// Returns the URL of an account on the chain, on the first explorer of the chain
+func accountURL(chain ChainData, addr string) string {
+    explorer := chain.Explorers[0]
+    return explorer.url(explorer.Account, chain, "address", addr)
+}
+
+// Returns the URL of a validator on the home chain, on the first explorer
+func validatorURL(addr string) string {
+    explorer := config.Chain.Explorers[0]
+    return explorer.url(explorer.Validator, config.Chain, "address", addr)
+}
+
+// Returns the URL of a governance proposal on the home chain, on the first explorer
+func proposalURL(id string) string {
+    explorer := config.Chain.Explorers[0]
+    return explorer.url(explorer.Proposal, config.Chain, "id", id)
+}
+
+// Returns the URL of the chain, on the first explorer of the chain
+func chainURL(chain ChainData) string {
+    explorer := chain.Explorers[0]
+    return explorer.url(explorer.Home, chain, "", "")
+}
+
+// Adds links to the TX on every explorer to the document, if more than one explorer is used
+func addExplorerLinks(doc *Document) {
+    if doc.Hash == "" || len(config.Chain.Explorers) < 2 {
+        return
+    }
+    var links []Text
+    for i, explorer := range config.Chain.Explorers {
+        if i > 0 {
+            links = append(links, plain(" | "))
+        }
+        links = append(links, Text{Text: explorer.Name, URL: explorer.url(explorer.TX, config.Chain, "hash", doc.Hash)})
+    }
+    doc.AddGroup("Explorers", links...)
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "math/big"
+    "os"
+    "path/filepath"
+    "regexp"
+    "slices"
+    "strconv"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+// A compiled filter expression, E.G. type == "Delegations" && (validator in watched || amount_usd > 5000) && !(memo =~ "spam")
+// Fields can have several values, a comparison is true if any of the values matches, except != and !~ which are
+// true if none of the values match
+type Expression struct {
+    Text string
+    root exprNode
+}
+
+// Expression every message has to match, and the expressions of the chats, keyed by their chat ID
+var filterExpression *Expression
+var chatExpressions = map[string]*Expression{}
+
+type exprNode interface {
+    eval(env *exprEnv) bool
+}
+
+type exprOr struct{ left, right exprNode }
+type exprAnd struct{ left, right exprNode }
+type exprNot struct{ node exprNode }
+type exprBool struct{ value bool }
+
+// A field without a comparison, true if the field has a value
+type exprField struct{ field string }
+
+// A comparison of a field against a string, a number, a regular expression or a list
+type exprCompare struct {
+    field  string
+    op     string
+    value  string
+    number *big.Rat
+    re     *regexp.Regexp
+    list   []string
+}
+
+func (n exprOr) eval(env *exprEnv) bool    { return n.left.eval(env) || n.right.eval(env) }
+func (n exprAnd) eval(env *exprEnv) bool   { return n.left.eval(env) && n.right.eval(env) }
+func (n exprNot) eval(env *exprEnv) bool   { return !n.node.eval(env) }
+func (n exprBool) eval(env *exprEnv) bool  { return n.value }
+func (n exprField) eval(env *exprEnv) bool { return len(env.values(n.field)) > 0 }
+
+func (n exprCompare) eval(env *exprEnv) bool {
+    switch n.op {
+    case "!=":
+        return !exprCompare{field: n.field, op: "==", value: n.value, number: n.number}.eval(env)
+    case "!~":
+        return !exprCompare{field: n.field, op: "=~", re: n.re}.eval(env)
+    }
+    for _, value := range env.values(n.field) {
+        if n.op == "=~" {
+            if n.re.MatchString(value) {
+                return true
+            }
+            continue
+        }
+        if n.op == "in" {
+            if slices.Contains(n.list, value) {
+                return true
+            }
+            continue
+        }
+        // Numbers are compared by their value, so 5000 matches 5000.0
+        if n.number != nil {
+            r, ok := new(big.Rat).SetString(value)
+            if !ok {
+                continue
+            }
+            cmp := r.Cmp(n.number)
+            if (n.op == "==" && cmp == 0) || (n.op == "<" && cmp < 0) || (n.op == "<=" && cmp <= 0) ||
+            (n.op == ">" && cmp > 0) || (n.op == ">=" && cmp >= 0) {
+                return true
+            }
+            continue
+        }
+        if value == n.value {
+            return true
+        }
+    }
+    return false
+}
+
+// The values of the fields of a message, collected when an expression first needs them
+type exprEnv struct {
+    msg    MessageResponse
+    fields map[string][]string
+}
+
+// Returns the values of a field, amount_<currency> is the value of the amount of the message in the currency
+func (env *exprEnv) values(field string) []string {
+    if currency, ok := strings.CutPrefix(field, "amount_"); ok && slices.Contains(config.Currencies, currency) {
+        amount := env.msg.Amount
+        if amount == "" {
+            amount = strings.Join(documentCoins(env.msg.Doc), ",")
+        }
+        // Like the amount filters, stale and unknown prices have no value
+        value, known, stale := amountValue(amount, currency)
+        if !known || stale {
+            return nil
+        }
+        return []string{trimDecimal(value)}
+    }
+    if env.fields == nil {
+        env.fields = messageFields(env.msg)
+    }
+    values := env.fields[field]
+    for _, alias := range fieldAliases[field] {
+        values = append(values, env.fields[alias]...)
+    }
+    return values
+}
+
+// Checks if the message matches the expression
+func (e *Expression) Matches(msg MessageResponse) bool {
+    return e.root.eval(&exprEnv{msg: msg})
+}
+
+// Returns the chats the message is routed to, chats without an expression get every message
+func routedChats(msg MessageResponse, chats []string) map[string]bool {
+    routed := map[string]bool{}
+    env := &exprEnv{msg: msg}
+    for _, chat := range chats {
+        expr, ok := chatExpressions[chat]
+        routed[chat] = !ok || expr.root.eval(env)
+        if !routed[chat] {
+            log.Println(color.YellowString(fmt.Sprintf("Filtered Message! Message of type %s did not match the expression of chat %s", msg.TypeName, chat)))
+        }
+    }
+    return routed
+}
+
+// Returns the fields expressions can use, the fields every message has, the fields of the message types' templates
+// and the amount in each currency
+func expressionFields() []string {
+    fields := slices.Clone(messageFieldNames)
+    for _, t := range messageTypes {
+        fields = append(fields, t.Fields...)
+    }
+    for _, currency := range config.Currencies {
+        fields = append(fields, "amount_" + currency)
+    }
+    slices.Sort(fields)
+    return slices.Compact(fields)
+}
+
+type exprToken struct {
+    kind string // ident, string, number, op or end
+    text string
+    pos  int
+}
+
+var exprTokenSyntax = regexp.MustCompile(`^(?:(&&|\|\||==|!=|=~|!~|<=|>=|[<>!()\[\],])|([A-Za-z_][A-Za-z0-9_.]*)|("(?:[^"\\]|\\.)*")|(-?[0-9]+(?:\.[0-9]+)?))`)
+
+// Splits an expression into its tokens
+func tokenizeExpression(text string) ([]exprToken, error) {
+    var tokens []exprToken
+    pos := 0
+    for {
+        for pos < len(text) && strings.ContainsRune(" \t\r\n", rune(text[pos])) {
+            pos++
+        }
+        if pos == len(text) {
+            return append(tokens, exprToken{kind: "end", pos: pos}), nil
+        }
+        match := exprTokenSyntax.FindStringSubmatch(text[pos:])
+        if match == nil {
+            return nil, fmt.Errorf("unexpected %q at position %d", text[pos:pos+1], pos+1)
+        }
+        token := exprToken{text: match[0], pos: pos}
+        switch {
+        case match[1] != "":
+            token.kind = "op"
+        case match[2] != "":
+            token.kind = "ident"
+        case match[3] != "":
+            token.kind = "string"
+            unquoted, err := strconv.Unquote(match[3])
+            if err != nil {
+                return nil, fmt.Errorf("invalid string %s at position %d", match[3], pos+1)
+            }
+            token.text = unquoted
+        default:
+            token.kind = "number"
+        }
+        tokens = append(tokens, token)
+        pos += len(match[0])
+    }
+}
+
+// Recursive descent parser of the expressions
+// expr       = and { "||" and }
+// and        = unary { "&&" unary }
+// unary      = "!" unary | "(" expr ")" | "true" | "false" | field [ comparison ]
+// comparison = ( "==" | "!=" ) ( string | number ) | ( "=~" | "!~" ) string | ( "<" | "<=" | ">" | ">=" ) number
+//            | "in" ( list | "[" string { "," string } "]" )
+type exprParser struct {
+    tokens []exprToken
+    pos    int
+    fields []string
+    lists  map[string][]string
+}
+
+// Compiles an expression, returns an error for syntax errors, unknown fields and lists, and invalid regular expressions
+func compileExpression(text string, fields []string, lists map[string][]string) (*Expression, error) {
+    tokens, err := tokenizeExpression(text)
+    if err != nil {
+        return nil, err
+    }
+    p := &exprParser{tokens: tokens, fields: fields, lists: lists}
+    root, err := p.parseOr()
+    if err != nil {
+        return nil, err
+    }
+    if tok := p.peek(); tok.kind != "end" {
+        return nil, p.unexpected(tok)
+    }
+    return &Expression{Text: text, root: root}, nil
+}
+
+func (p *exprParser) peek() exprToken {
+    return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+    tok := p.tokens[p.pos]
+    if tok.kind != "end" {
+        p.pos++
+    }
+    return tok
+}
+
+// Consumes the token if it is the operator
+func (p *exprParser) accept(op string) bool {
+    if tok := p.peek(); tok.kind == "op" && tok.text == op {
+        p.pos++
+        return true
+    }
+    return false
+}
+
+func (p *exprParser) unexpected(tok exprToken) error {
+    if tok.kind == "end" {
+        return fmt.Errorf("unexpected end of expression")
+    }
+    return fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+    left, err := p.parseAnd()
+    if err != nil {
+        return nil, err
+    }
+    for p.accept("||") {
+        right, err := p.parseAnd()
+        if err != nil {
+            return nil, err
+        }
+        left = exprOr{left, right}
+    }
+    return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+    left, err := p.parseUnary()
+    if err != nil {
+        return nil, err
+    }
+    for p.accept("&&") {
+        right, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        left = exprAnd{left, right}
+    }
+    return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+    if p.accept("!") {
+        node, err := p.parseUnary()
+        if err != nil {
+            return nil, err
+        }
+        return exprNot{node}, nil
+    }
+    if p.accept("(") {
+        node, err := p.parseOr()
+        if err != nil {
+            return nil, err
+        }
+        if !p.accept(")") {
+            return nil, p.unexpected(p.peek())
+        }
+        return node, nil
+    }
+    tok := p.next()
+    if tok.kind != "ident" {
+        return nil, p.unexpected(tok)
+    }
+    switch tok.text {
+    case "true", "false":
+        return exprBool{tok.text == "true"}, nil
+    }
+    if !isFilterField(tok.text, p.fields) {
+        return nil, fmt.Errorf("unknown field %s at position %d", tok.text, tok.pos+1)
+    }
+    return p.parseComparison(tok.text)
+}
+
+func (p *exprParser) parseComparison(field string) (exprNode, error) {
+    op := p.peek()
+    if op.kind == "ident" && op.text == "in" {
+        p.next()
+        list, err := p.parseList()
+        if err != nil {
+            return nil, err
+        }
+        return exprCompare{field: field, op: "in", list: list}, nil
+    }
+    if op.kind != "op" || !slices.Contains([]string{"==", "!=", "=~", "!~", "<", "<=", ">", ">="}, op.text) {
+        return exprField{field}, nil
+    }
+    p.next()
+    value := p.next()
+    node := exprCompare{field: field, op: op.text, value: value.text}
+    switch op.text {
+    case "==", "!=":
+        if value.kind != "string" && value.kind != "number" {
+            return nil, p.unexpected(value)
+        }
+    case "=~", "!~":
+        if value.kind != "string" {
+            return nil, p.unexpected(value)
+        }
+        re, err := regexp.Compile(value.text)
+        if err != nil {
+            return nil, fmt.Errorf("invalid regular expression at position %d: %s", value.pos+1, err)
+        }
+        node.re = re
+    default:
+        if value.kind != "number" {
+            return nil, fmt.Errorf("%s needs a number at position %d", op.text, value.pos+1)
+        }
+    }
+    if value.kind == "number" {
+        node.number, _ = new(big.Rat).SetString(value.text)
+    }
+    return node, nil
+}
+
+// Parses the list of an in comparison, a list of the config or a list of strings
+func (p *exprParser) parseList() ([]string, error) {
+    tok := p.next()
+    if tok.kind == "ident" {
+        list, ok := p.lists[tok.text]
+        if !ok {
+            return nil, fmt.Errorf("unknown list %s at position %d", tok.text, tok.pos+1)
+        }
+        return list, nil
+    }
+    if tok.kind != "op" || tok.text != "[" {
+        return nil, p.unexpected(tok)
+    }
+    var list []string
+    for {
+        item := p.next()
+        if item.kind != "string" {
+            return nil, p.unexpected(item)
+        }
+        list = append(list, item.text)
+        if p.accept("]") {
+            return list, nil
+        }
+        if !p.accept(",") {
+            return nil, p.unexpected(p.peek())
+        }
+    }
+}
+
+// Returns the directory of the websocket responses used by -test-filter, the one set with -json-outputs or else
+// debug/jsonoutputs of the repository, looked up next to the binary, the config and the working directory
+func jsonOutputsDir() string {
+    if jsonoutputs != "" {
+        if info, err := os.Stat(jsonoutputs); err != nil || !info.IsDir() {
+            log.Fatal(color.RedString("The json outputs directory " + jsonoutputs + " does not exist"))
+        }
+        return jsonoutputs
+    }
+    var dirs []string
+    if exe, err := os.Executable(); err == nil {
+        // Binaries are built in the repository or in src
+        dirs = append(dirs, filepath.Join(filepath.Dir(exe), "debug", "jsonoutputs"), filepath.Join(filepath.Dir(exe), "..", "debug", "jsonoutputs"))
+    }
+    dirs = append(dirs, filepath.Join(strings.TrimSuffix(configpath, "config.toml"), "jsonoutputs"),
+        filepath.Join("debug", "jsonoutputs"), filepath.Join("..", "debug", "jsonoutputs"))
+    for _, dir := range dirs {
+        if info, err := os.Stat(dir); err == nil && info.IsDir() {
+            return dir
+        }
+    }
+    log.Fatal(color.RedString("Could not find the json outputs in " + strings.Join(dirs, ", ") + ", set their directory with -json-outputs"))
+    return ""
+}
+
+var errOffline = errors.New("offline, not queried")
+
+// Fetcher which answers every query with errOffline, so testFilter runs without the network
+func fetchOffline(url string, height string, container interface{}) error {
+    return errOffline
+}
+
+// Evaluates the expression against the messages of the websocket responses in the json outputs directory,
+// printing if each message matches. The messages still go through the filters of their type
+// Queries go to the fetcher, with fetchOffline only names, tokens and prices of the config are known
+func testFilter(text string, fetcher Fetcher) {
+    fetch = fetcher
+    expr, err := compileExpression(text, expressionFields(), config.Config.ExpressionsConfig.Lists)
+    if err != nil {
+        log.Fatal(color.RedString(fmt.Sprintf("Invalid filter expression: %s", err)))
+    }
+    dir := jsonOutputsDir()
+    files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+    if len(files) == 0 {
+        log.Fatal(color.RedString("No json outputs found in " + dir))
+    }
+    // Only the tested expression is evaluated, and transfers aren't held back for distributions
+    filterExpression = nil
+    config.Config.DistributionsConfig.Enabled = false
+    matched, total := 0, 0
+    for _, file := range files {
+        f, err := os.Open(file)
+        if err != nil {
+            log.Println(color.YellowString("Could not open %s: %v", file, err))
+            continue
+        }
+        // A file can hold several responses
+        decoder := json.NewDecoder(f)
+        for {
+            var m json.RawMessage
+            if err := decoder.Decode(&m); err != nil {
+                if err != io.EOF {
+                    log.Println(color.YellowString("Could not read %s: %v", file, err))
+                }
+                break
+            }
+            res, err := parseWebsocketResponse(m)
+            if err != nil {
+                log.Println(color.YellowString("Could not unmarshal %s: %v", file, err))
+                continue
+            }
+            resp := make(chan MessageResponse)
+            go func() {
+                handleTx(res, resp)
+                close(resp)
+            }()
+            for msg := range resp {
+                total++
+                result := color.YellowString("no match")
+                if expr.Matches(msg) {
+                    matched++
+                    result = color.GreenString("match")
+                }
+                fmt.Printf("%s: %s %s %s\n", filepath.Base(file), msg.TypeName, msg.Doc.Hash, result)
+            }
+        }
+        f.Close()
+    }
+    fmt.Printf("%d of %d messages matched %s\n", matched, total, text)
+}
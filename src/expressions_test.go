@@ -0,0 +1,126 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+var testExprFields = []string{"type", "hash", "memo", "amount", "denom", "sender", "recipient", "validator", "delegator", "source", "count"}
+
+var testExprLists = map[string][]string{"watched": {"undvaloper1watched", "undvaloper1other"}}
+
+func TestCompileExpressionErrors(t *testing.T) {
+    tests := []struct {
+        text string
+        err  string
+    }{
+        {"", "unexpected end of expression"},
+        {"type ==", "unexpected end of expression"},
+        {"type == Delegations", `unexpected "Delegations" at position 9`},
+        {"(type == \"Transfer\"", "unexpected end of expression"},
+        {"type == \"Transfer\")", `unexpected ")" at position 19`},
+        {"type == \"Transfer\" &&", "unexpected end of expression"},
+        {"type = \"Transfer\"", `unexpected "=" at position 6`},
+        {"fee > 5", "unknown field fee at position 1"},
+        {"amount_eur > 5", "unknown field amount_eur at position 1"},
+        {"amount > \"5\"", "> needs a number at position 10"},
+        {"memo =~ 5", `unexpected "5" at position 9`},
+        {"memo =~ \"(\"", "invalid regular expression at position 9"},
+        {"validator in unwatched", "unknown list unwatched at position 14"},
+        {"validator in [\"a\" \"b\"]", `unexpected "b" at position 19`},
+        {"validator in []", `unexpected "]" at position 15`},
+        {"type == \"Transfer\" $", `unexpected "$" at position 20`},
+        {"memo == \"\\q\"", "invalid string"},
+    }
+    for _, tt := range tests {
+        _, err := compileExpression(tt.text, testExprFields, testExprLists)
+        if err == nil {
+            t.Errorf("compileExpression(%q) compiled, want the error %q", tt.text, tt.err)
+            continue
+        }
+        if !strings.Contains(err.Error(), tt.err) {
+            t.Errorf("compileExpression(%q) error = %q, want %q", tt.text, err, tt.err)
+        }
+    }
+}
+
+func TestExpressionMatches(t *testing.T) {
+    useTestChains(t)
+    var msg MessageResponse
+    msg.TypeName = "Redelegations"
+    msg.Data = MessageData{
+        "source": "undvaloper1watched",
+        "delegator": "und1delegator",
+        "memo": "Claim your airdrop",
+        "count": "12",
+    }
+    msg.Doc.Add("Delegator", Text{Text: "Cosmostation", Account: "und1delegator"})
+    msg.Doc.Add("Amount", Text{Coins: "5000500000000000nund"})
+    tests := []struct {
+        text  string
+        match bool
+    }{
+        {"true", true},
+        {"false", false},
+        {"type == \"Redelegations\"", true},
+        {"type == \"Delegations\"", false},
+        {"type != \"Delegations\"", true},
+        // Fields have several values, != is true if none of them match
+        {"delegator == \"Cosmostation\"", true},
+        {"delegator == \"und1delegator\"", true},
+        {"delegator != \"Cosmostation\"", false},
+        {"delegator =~ \"^Cosmo\"", true},
+        {"delegator !~ \"^Cosmo\"", false},
+        {"memo =~ \"(?i)AIRDROP\"", true},
+        {"memo !~ \"spam\"", true},
+        // Aliases, the source of a redelegation is a validator
+        {"validator in watched", true},
+        {"validator in [\"undvaloper1x\", \"undvaloper1watched\"]", true},
+        {"validator in [\"undvaloper1x\"]", false},
+        // Numbers compare by value
+        {"amount == 5000500", true},
+        {"amount == 5000500.0", true},
+        {"amount > 5000", true},
+        {"amount >= 5000500", true},
+        {"amount < 5000500", false},
+        {"amount <= 5000500", true},
+        {"count > 9", true},
+        {"denom == \"FUND\"", true},
+        // Fields without a comparison are true when they have a value
+        {"memo", true},
+        {"recipient", false},
+        {"!recipient", true},
+        // && binds tighter than ||
+        {"type == \"Delegations\" || type == \"Redelegations\" && validator in watched", true},
+        {"(type == \"Delegations\" || type == \"Redelegations\") && !(memo =~ \"airdrop\")", false},
+        {"type == \"Delegations\" || false && true", false},
+        {"!!true", true},
+    }
+    for _, tt := range tests {
+        expr, err := compileExpression(tt.text, testExprFields, testExprLists)
+        if err != nil {
+            t.Errorf("compileExpression(%q) failed: %v", tt.text, err)
+            continue
+        }
+        if got := expr.Matches(msg); got != tt.match {
+            t.Errorf("%q matches = %v, want %v", tt.text, got, tt.match)
+        }
+    }
+}
+
+func TestRoutedChats(t *testing.T) {
+    saved := chatExpressions
+    t.Cleanup(func() { chatExpressions = saved })
+    transfers, _ := compileExpression("type == \"Transfer\"", testExprFields, nil)
+    delegations, _ := compileExpression("type == \"Delegations\"", testExprFields, nil)
+    chatExpressions = map[string]*Expression{"-100": transfers, "-200": delegations}
+    var msg MessageResponse
+    msg.TypeName = "Transfer"
+    routed := routedChats(msg, []string{"-100", "-200", "-300"})
+    want := map[string]bool{"-100": true, "-200": false, "-300": true}
+    for chat, ok := range want {
+        if routed[chat] != ok {
+            t.Errorf("chat %s routed = %v, want %v", chat, routed[chat], ok)
+        }
+    }
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+    "math/big"
+    "regexp"
+    "slices"
+    "strings"
+)
+
+// A rule of the list of a message type, matching a field of the message
+// E.G. "sender = und1...", "denom ^= ibc/" or "memo ~= (?i)airdrop". Rules without a known field and operator,
+// E.G. "und1..." or "Delegate(rewards)", match any field of the message containing them
+type FilterRule struct {
+    Text  string
+    Field string
+    // "=" for exact matches, "^=" for prefixes, "~=" for regular expressions and "" for substrings
+    Op    string
+    Value string
+    re    *regexp.Regexp
+}
+
+// Compiled rules of the lists of the message types, keyed by their type name
+var filterRules = map[string][]FilterRule{}
+
+var ruleSyntax = regexp.MustCompile(`^([a-z_]+)\s*(=|\^=|~=)\s*(.*)$`)
+
+// Items which likely match more than intended as substrings, E.G. 100 also matches 100100 and hashes containing 100
+var numericItem = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+var addressItem = regexp.MustCompile(`^[a-z]+1[02-9ac-hj-np-z]{38,}$`)
+
+// Fields every message has, besides the fields of its template
+var messageFieldNames = []string{"type", "action", "hash", "memo", "denom", "amount",
+    "sender", "recipient", "validator", "delegator"}
+
+// Other fields also matched by a field, E.G. the source and destination of a redelegation are validators
+var fieldAliases = map[string][]string{
+    "sender":    {"signer"},
+    "recipient": {"final_recipient"},
+    "validator": {"source", "destination"},
+}
+
+// Parses a rule of a list, returns an error for invalid regular expressions
+// The fields are the fields every message has, and the fields of the template of the message type. Items which
+// don't start with one of the fields, like a memo containing =, are matched as text
+func parseFilterRule(text string, fields []string) (FilterRule, error) {
+    match := ruleSyntax.FindStringSubmatch(strings.TrimSpace(text))
+    if match == nil || !isFilterField(match[1], fields) {
+        return FilterRule{Text: text, Value: text}, nil
+    }
+    rule := FilterRule{Text: text, Field: match[1], Op: match[2], Value: match[3]}
+    if rule.Op == "~=" {
+        re, err := regexp.Compile(rule.Value)
+        if err != nil {
+            return rule, err
+        }
+        rule.re = re
+    }
+    return rule, nil
+}
+
+// Returns a warning for items matched as text which look like an amount or an address, they match any field
+// containing them, so a field with = should be used instead
+func (rule FilterRule) warning() (string, bool) {
+    if rule.Field != "" {
+        return "", false
+    }
+    value := strings.TrimSpace(rule.Value)
+    switch {
+    case numericItem.MatchString(value):
+        return "matches any field containing it, like a hash or a larger amount, use \"amount = " + value + "\" to match the amount exactly", true
+    case addressItem.MatchString(value):
+        return "matches any field containing it, use a field like \"sender = " + value + "\" to match the address exactly", true
+    }
+    return "", false
+}
+
+// Checks if a field can be matched, fields of lists are matched by their own name, E.G. recipient for
+// recipients.recipient
+func isFilterField(field string, fields []string) bool {
+    if slices.Contains(messageFieldNames, field) {
+        return true
+    }
+    for _, name := range fields {
+        name = strings.TrimSuffix(name, "[]")
+        if name == field || strings.HasSuffix(name, "." + field) || strings.HasPrefix(name, field + ".") {
+            return true
+        }
+    }
+    return false
+}
+
+// Checks if the rule matches a value of the fields of the message
+func (rule FilterRule) matches(fields map[string][]string) bool {
+    var values []string
+    if rule.Field == "" {
+        for _, v := range fields {
+            values = append(values, v...)
+        }
+    } else {
+        values = fields[rule.Field]
+        for _, alias := range fieldAliases[rule.Field] {
+            values = append(values, fields[alias]...)
+        }
+    }
+    for _, value := range values {
+        switch rule.Op {
+        case "^=":
+            if strings.HasPrefix(value, rule.Value) {
+                return true
+            }
+        case "~=":
+            if rule.re.MatchString(value) {
+                return true
+            }
+        case "=":
+            if value == rule.Value {
+                return true
+            }
+        default:
+            if strings.Contains(value, rule.Value) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// Returns the values of the fields of a message, from the fields of its template and its document
+// Addresses are matched by their name too, amounts by their display amount and coin, and denoms by the denom,
+// the base denom and the display name of the coins
+func messageFields(msg MessageResponse) map[string][]string {
+    fields := map[string][]string{}
+    add := func(field string, value string) {
+        if value != "" {
+            fields[field] = append(fields[field], value)
+        }
+    }
+    add("type", msg.TypeName)
+    for key, value := range msg.Data {
+        switch v := value.(type) {
+        case string:
+            add(key, v)
+        case []string:
+            for _, item := range v {
+                add(key, item)
+            }
+        case []map[string]string:
+            for _, item := range v {
+                for k, val := range item {
+                    add(k, val)
+                }
+            }
+        }
+    }
+    names := map[string]string{}
+    for _, field := range msg.Doc.Fields {
+        for _, line := range field.Lines {
+            for _, t := range line {
+                if t.Account != "" && t.Text != t.Account {
+                    names[t.Account] = t.Text
+                }
+            }
+        }
+    }
+    for field, values := range fields {
+        for _, value := range values {
+            if name, ok := names[value]; ok {
+                add(field, name)
+            }
+        }
+    }
+    for _, coin := range documentCoins(msg.Doc) {
+        amount, denom := splitAmountDenom(coin)
+        add("amount", coin)
+        add("denom", denom)
+        if resolved, ok := resolveCoin(coin); ok {
+            add("amount", trimDecimal(resolved.Display))
+            add("denom", resolved.Asset.Denom)
+            add("denom", resolved.Symbol)
+        } else {
+            add("amount", amount.String())
+        }
+    }
+    for field := range fields {
+        slices.Sort(fields[field])
+        fields[field] = slices.Compact(fields[field])
+    }
+    return fields
+}
+
+// Returns the coins of the amounts of a document
+func documentCoins(doc Document) []string {
+    var coins []string
+    for _, field := range doc.Fields {
+        for _, line := range field.Lines {
+            for _, t := range line {
+                if t.Coins != "" {
+                    coins = append(coins, splitCoins(t.Coins)...)
+                }
+            }
+        }
+    }
+    return coins
+}
+
+// Formats a number without trailing zeros or thousands separators, E.G. 100100 or 0.5
+func trimDecimal(r *big.Rat) string {
+    str := r.FloatString(18)
+    str = strings.TrimRight(str, "0")
+    return strings.TrimSuffix(str, ".")
+}
+
@@ -0,0 +1,195 @@
+package main
+
+import (
+    "math/big"
+    "slices"
+    "testing"
+)
+
+func TestParseFilterRule(t *testing.T) {
+    fields := []string{"sender", "recipient", "recipients.amount", "added[]"}
+    tests := []struct {
+        text  string
+        field string
+        op    string
+        value string
+        err   bool
+    }{
+        {text: "sender = und1abc", field: "sender", op: "=", value: "und1abc"},
+        {text: "  sender=und1abc", field: "sender", op: "=", value: "und1abc"},
+        {text: "denom ^= ibc/", field: "denom", op: "^=", value: "ibc/"},
+        {text: "memo ~= (?i)airdrop", field: "memo", op: "~=", value: "(?i)airdrop"},
+        // Fields of lists are matched by their own name
+        {text: "amount = 5", field: "amount", op: "=", value: "5"},
+        {text: "added = BTC: bc1abc", field: "added", op: "=", value: "BTC: bc1abc"},
+        // Items without a known field are matched as text
+        {text: "und1abc", value: "und1abc"},
+        {text: "Delegate(rewards)", value: "Delegate(rewards)"},
+        {text: "gift = 100", value: "gift = 100"},
+        {text: "a=b", value: "a=b"},
+        {text: "memo ~= (unclosed", err: true},
+    }
+    for _, tt := range tests {
+        rule, err := parseFilterRule(tt.text, fields)
+        if tt.err {
+            if err == nil {
+                t.Errorf("parseFilterRule(%q) = %+v, want an error", tt.text, rule)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("parseFilterRule(%q) failed: %v", tt.text, err)
+            continue
+        }
+        if rule.Field != tt.field || rule.Op != tt.op || rule.Value != tt.value {
+            t.Errorf("parseFilterRule(%q) = %q %q %q, want %q %q %q", tt.text, rule.Field, rule.Op, rule.Value, tt.field, tt.op, tt.value)
+        }
+    }
+}
+
+func TestFilterRuleMatches(t *testing.T) {
+    fields := map[string][]string{
+        "type":      {"Redelegations"},
+        "hash":      {"AB100CD"},
+        "amount":    {"100100", "100100000000000nund"},
+        "denom":     {"FUND", "nund"},
+        "memo":      {"Claim your AIRDROP now"},
+        "delegator": {"und1delegator", "Cosmostation"},
+        "source":    {"undvaloper1source"},
+    }
+    tests := []struct {
+        text  string
+        match bool
+    }{
+        {"amount = 100100", true},
+        {"amount = 100", false},
+        {"amount ^= 1001", true},
+        {"amount ~= ^100100$", true},
+        {"amount ~= ^100$", false},
+        {"denom = FUND", true},
+        {"denom = fund", false},
+        {"denom ^= ibc/", false},
+        {"memo ~= (?i)airdrop", true},
+        {"memo ~= airdrop", false},
+        {"delegator = Cosmostation", true},
+        {"delegator = und1delegator", true},
+        {"sender = und1delegator", false},
+        // Aliases, the source of a redelegation is a validator
+        {"validator = undvaloper1source", true},
+        // Bare items match any field containing them
+        {"100", true},
+        {"AIRDROP", true},
+        {"Cosmo", true},
+        {"nothing", false},
+    }
+    for _, tt := range tests {
+        rule, err := parseFilterRule(tt.text, []string{"source"})
+        if err != nil {
+            t.Fatalf("parseFilterRule(%q) failed: %v", tt.text, err)
+        }
+        if got := rule.matches(fields); got != tt.match {
+            t.Errorf("%q matches = %v, want %v", tt.text, got, tt.match)
+        }
+    }
+}
+
+func TestFilterRuleWarning(t *testing.T) {
+    tests := []struct {
+        text string
+        warn bool
+    }{
+        {"100", true},
+        {"100.5", true},
+        {"und1hdn830wndtquqxzaz3rds7r7hqgpsg5q9ggxpk", true},
+        {"amount = 100", false},
+        {"sender = und1hdn830wndtquqxzaz3rds7r7hqgpsg5q9ggxpk", false},
+        {"Cosmostation", false},
+        {"Delegate(rewards)", false},
+        {"und1short", false},
+    }
+    for _, tt := range tests {
+        rule, _ := parseFilterRule(tt.text, nil)
+        if _, warn := rule.warning(); warn != tt.warn {
+            t.Errorf("%q warning = %v, want %v", tt.text, warn, tt.warn)
+        }
+    }
+}
+
+func TestIsAllowedFields(t *testing.T) {
+    fields := func() map[string][]string {
+        return map[string][]string{"sender": {"und1abc"}, "amount": {"5"}}
+    }
+    rules := func(items ...string) []FilterRule {
+        var rules []FilterRule
+        for _, item := range items {
+            rule, _ := parseFilterRule(item, nil)
+            rules = append(rules, rule)
+        }
+        return rules
+    }
+    tests := []struct {
+        name    string
+        filter  string
+        rules   []FilterRule
+        allowed bool
+    }{
+        {name: "default", filter: "default", rules: rules("sender = und1abc"), allowed: true},
+        {name: "blacklisted", filter: "blacklist", rules: rules("sender = und1xyz", "sender = und1abc"), allowed: false},
+        {name: "not blacklisted", filter: "blacklist", rules: rules("sender = und1xyz"), allowed: true},
+        {name: "whitelisted", filter: "whitelist", rules: rules("amount = 5"), allowed: true},
+        {name: "not whitelisted", filter: "whitelist", rules: rules("amount = 50"), allowed: false},
+        {name: "empty whitelist", filter: "whitelist", allowed: false},
+    }
+    for _, tt := range tests {
+        if got := isAllowedFields(MessageConfig{Filter: tt.filter}, tt.rules, "Test", fields); got != tt.allowed {
+            t.Errorf("%s: allowed = %v, want %v", tt.name, got, tt.allowed)
+        }
+    }
+}
+
+func TestMessageFields(t *testing.T) {
+    useTestChains(t)
+    var msg MessageResponse
+    msg.TypeName = "Transfer"
+    msg.Data = MessageData{
+        "sender": "und1sender",
+        "recipients": []map[string]string{{"recipient": "und1recipient", "amount": "5nund"}},
+        "added": []string{"BTC: bc1abc"},
+        "memo": "hello",
+    }
+    msg.Doc.Add("Sender", Text{Text: "Cosmostation", Account: "und1sender"})
+    msg.Doc.Add("Amount", Text{Coins: "100100000000000nund,7ufoo"})
+    fields := messageFields(msg)
+    want := map[string][]string{
+        "type":      {"Transfer"},
+        "sender":    {"Cosmostation", "und1sender"},
+        "recipient": {"und1recipient"},
+        "added":     {"BTC: bc1abc"},
+        "memo":      {"hello"},
+        "amount":    {"100100", "100100000000000nund", "5nund", "7", "7ufoo"},
+        "denom":     {"FUND", "nund", "ufoo"},
+    }
+    for field, values := range want {
+        if !slices.Equal(fields[field], values) {
+            t.Errorf("%s = %q, want %q", field, fields[field], values)
+        }
+    }
+}
+
+func TestTrimDecimal(t *testing.T) {
+    tests := []struct {
+        value string
+        want  string
+    }{
+        {"100100", "100100"},
+        {"0.5", "0.5"},
+        {"1/3", "0.333333333333333333"},
+        {"0", "0"},
+    }
+    for _, tt := range tests {
+        r, _ := new(big.Rat).SetString(tt.value)
+        if got := trimDecimal(r); got != tt.want {
+            t.Errorf("trimDecimal(%s) = %s, want %s", tt.value, got, tt.want)
+        }
+    }
+}
@@ -2,40 +2,106 @@ package main
 
 import (
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math"
-    "regexp"
-	"strconv"
+	"slices"
 	"strings"
-    "time"
-    "math/rand"
+	"time"
+    "math/big"
 
 	"github.com/fatih/color"
 	"github.com/btcsuite/btcutil/bech32"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
 )
 
 
-// Returns and MD formatted hyperlink for an account when given a wallet or validator address
-func mkAccountLink(addr string) string{
-    if addr[:len(config.Chain.Prefix + "val")] == config.Chain.Prefix + "val"{
-        return fmt.Sprintf("[%s](%s%s)",getAccountName(addr),config.Explorer.Validator,addr)
+// Returns a link to an account when given a wallet or validator address
+func accountText(addr string) Text {
+    // Not an address, E.G. the placeholder receiver "pfm" of forwarded IBC transfers
+    if len(addr) < 15 {
+        return plain(addr)
+    }
+    if strings.HasPrefix(addr, config.Chain.Prefix + "val") {
+        return Text{Text: getAccountName(addr), URL: validatorURL(addr), Account: addr}
     } else {
         for _, chain := range(config.OtherChains) {
-            if chain.Prefix == addr[:len(chain.Prefix)] {
-                url := config.Explorer.Base + chain.ExplorerPath + "/account/" + addr
-                return fmt.Sprintf("[%s](%s)",getAccountName(addr),url)
+            if strings.HasPrefix(addr, chain.Prefix) {
+                return Text{Text: getAccountName(addr), URL: accountURL(chain, addr), Account: addr}
             }
         }
-        return fmt.Sprintf("[%s](%s%s)",getAccountName(addr),config.Explorer.Account, addr)
+        return Text{Text: getAccountName(addr), URL: accountURL(config.Chain, addr), Account: addr}
+    }
+}
+
+// Returns the memo of the TX of a websocket response, decoded from the TX when possible, otherwise from the
+// rest endpoints
+func txMemo(res WebsocketResponse) string {
+    // The body is the first field of the TX, and the memo the second field of the body
+    body, ok := protoField(res.Result.Data.Value.TxResult.Tx, 1)
+    if !ok {
+        if len(res.Result.Events.TxHash) == 0 {
+            return ""
+        }
+        return getMemo(res.Result.Events.TxHash[0])
     }
+    memo, _ := protoField(body, 2)
+    if _, ok := parsePacketMemo(memo); ok {
+        return ""
+    }
+    return string(memo)
+}
+
+// Returns a length delimited field of a protobuf message, false if the message doesn't have the field or
+// can't be decoded
+func protoField(msg []byte, field uint64) ([]byte, bool) {
+    for len(msg) > 0 {
+        key, n := binary.Uvarint(msg)
+        if n <= 0 {
+            return nil, false
+        }
+        msg = msg[n:]
+        switch key & 7 {
+        case 0:
+            if _, n = binary.Uvarint(msg); n <= 0 {
+                return nil, false
+            }
+            msg = msg[n:]
+        case 1, 5:
+            size := 8
+            if key & 7 == 5 {
+                size = 4
+            }
+            if len(msg) < size {
+                return nil, false
+            }
+            msg = msg[size:]
+        case 2:
+            length, n := binary.Uvarint(msg)
+            if n <= 0 || length > uint64(len(msg) - n) {
+                return nil, false
+            }
+            value := msg[n:n+int(length)]
+            msg = msg[n+int(length):]
+            if key >> 3 == field {
+                return value, true
+            }
+        default:
+            return nil, false
+        }
+    }
+    return nil, false
+}
+
+// Returns a link to a transaction when given a TX Hash with an amount
+func amountText(hash string, amount string) Text {
+    return Text{Coins: amount, URL: txURL(hash)}
 }
 
-// Returns a MD formatted hyprlink for a transaction when given a TX Hash with an amount
-func mkTranscationLink(hash string, amount string) string {
-    return fmt.Sprintf("[%s](%s%s)", denomToAmount(amount), config.Explorer.TX,hash)
+// Formatted amount of coins, in the currency the message is shown in
+func coinsText(coins string) Text {
+    return Text{Coins: coins}
 }
 
 // When given a transaction hash
@@ -47,7 +113,54 @@ func getMemo(hash string) string {
         log.Println(color.YellowString("Failed to get TX rest response: ", err))
         return ""
     }
-    return removeForbiddenChars(tx.Tx.Body.Memo)
+    // Forward and wasm memos are shown as the route of the transfer instead
+    if _, ok := parsePacketMemo([]byte(tx.Tx.Body.Memo)); ok {
+        return ""
+    }
+    return tx.Tx.Body.Memo
+}
+
+// Returns a link to a transaction when given a TX Hash, for messages without an amount
+func hashText(hash string) Text {
+    if len(hash) < 15 {
+        return Text{Text: hash, URL: txURL(hash)}
+    }
+    return Text{Text: hash[:7] + "..." + hash[len(hash)-7:], URL: txURL(hash)}
+}
+
+// When given a transaction hash and a message type URL
+// Searches rest endpoints for the first message of that type in the transaction, and unmarshals it into the container
+func getTxMessage(hash string, action string, container interface{}) error {
+    var tx TxResponse
+    if err := getData(config.Connections.Rest + "cosmos/tx/v1beta1/txs/" + hash, &tx); err != nil {
+        return err
+    }
+    for _, raw := range tx.Tx.Body.Messages {
+        var msg struct {
+            Type string `json:"@type"`
+        }
+        if err := json.Unmarshal(raw, &msg); err != nil || msg.Type != action {
+            continue
+        }
+        return json.Unmarshal(raw, container)
+    }
+    return errors.New("Message " + action + " not found in TX: " + hash)
+}
+
+// Returns the account address of a validators' operator address, E.G. undval1... becomes und1...
+// Returns an empty string if the address can't be converted
+func valAccountAddr(operator string) string {
+    _, data, err := bech32.Decode(operator)
+    if err != nil {
+        log.Println(color.YellowString("Could not decode bech32 address"))
+        return ""
+    }
+    addr, err := bech32.Encode(config.Chain.Prefix,data)
+    if err != nil {
+        log.Println(color.YellowString("Could not encode bech32 address"))
+        return ""
+    }
+    return addr
 }
 
 // When given a wallet or validator address, returns the name associated with the wallet, if it has one
@@ -57,14 +170,8 @@ func getAccountName(msg string) string {
     names := map[string][]string{}
     // Convert undval to und1 addresses and append to map
     for _, val := range vals.Validators {
-        _, data, err := bech32.Decode(val.OperatorAddress)
-        if err != nil {
-            log.Println(color.YellowString("Could not decode bech32 address"))
-            continue
-        }
-        addr, err := bech32.Encode(config.Chain.Prefix,data)
-        if err != nil {
-            log.Println(color.YellowString("Could not encode bech32 address"))
+        addr := valAccountAddr(val.OperatorAddress)
+        if addr == "" {
             continue
         }
         names[val.Description.Moniker] = []string{addr, val.OperatorAddress}
@@ -73,14 +180,14 @@ func getAccountName(msg string) string {
     // Check if name matches named wallet from config
     for _, name := range config.Config.AddressesConfig.Addresses {
         if name.Addr == msg {
-            return removeForbiddenChars(name.Name)
+            return name.Name
         }
     }
 
     // Check if name matches wallet or val addr
     for key, val := range names {
         if val[0] == msg || val[1] == msg {
-            return removeForbiddenChars(key)
+            return key
         }
     }
 
@@ -91,76 +198,169 @@ func getAccountName(msg string) string {
     err := getData(config.Connections.ICNS +
         "cosmwasm/wasm/v1/contract/osmo1xk0s8xgktn9x5vwcgtjdxqzadg88fgn33p8u9cnpdxwemvxscvast52cdd/smart/" +
         b64, &icns)
-    if err != nil {
+    if err != nil && err != errOffline {
         log.Println(color.YellowString("Failed to get ICNS response ", err))
     }
     if icns.Data.PrimaryName != "" {
-        return removeForbiddenChars(icns.Data.PrimaryName)
+        return icns.Data.PrimaryName
     }
 
     // Return truncated addr if the addr isnt in the named map
     return fmt.Sprintf("%s...%s",msg[:7],msg[len(msg)-7:])
 }
 
+// Returns a function summing amounts, which returns the running total as a list of coins
+// E.G. 100nund then 5uatom,50nund totals 150nund,5uatom
 func denomTotaler() func(string) string{
-    var total float64
+    totals := map[string]*big.Int{}
+    var denoms []string
     return func(msg string) string {
-        amount, denom := splitAmountDenom(msg)
-        total += amount
-        return fmt.Sprintf("%.0f%s",total,denom)
+        for _, coin := range splitCoins(msg) {
+            amount, denom := splitAmountDenom(coin)
+            if _, ok := totals[denom]; !ok {
+                totals[denom] = new(big.Int)
+                denoms = append(denoms, denom)
+            }
+            totals[denom].Add(totals[denom], amount)
+        }
+        var coins []string
+        for _, denom := range denoms {
+            coins = append(coins, totals[denom].String() + denom)
+        }
+        return strings.Join(coins, ",")
     }
 }
 
-// Converts the denom to the formatted amount
-// E.G. 1000000000nund becomes 1.00 FUND
-// TODO Find a way to add currency amounts to IBC's, without overloading the CoinGecko API
-func denomToAmount(msg string) string {
-    amount, denom := splitAmountDenom(msg)
-    // This will format the numbers in human readable form E.G.
-    // 1000 FUND should become 1,000 FUND
-    formatter := message.NewPrinter(language.English)
+// A coin resolved to its display denom, and the asset to price it with
+type resolvedCoin struct {
+    Display *big.Rat
+    // Display name of the denom, E.G. ATOM, and the name shown in the messages, E.G. ATOM via transfer/channel-0
+    Symbol  string
+    Name    string
+    Asset   PricedAsset
+}
+
+// Resolves a coin, which can be the chains' coin, its other assets or IBC tokens
+// Returns false if the denom isn't known
+func resolveCoin(coin string) (resolvedCoin, bool) {
+    amount, denom := splitAmountDenom(coin)
     if denom == config.Chain.Denom {
-        exp, _ := strconv.ParseFloat("1" + strings.Repeat("0",config.Chain.Exponent), 64)
-        amount = math.Round((amount/exp)*100)/100
-        return formatter.Sprintf("%.2f %s (%.2f %s)", amount, config.Chain.DisplayName ,(*config.Chain.CoinGeckoData.Price * amount), config.Currency)
-    } else if denom[:4] == "ibc/" {
-        amount, denom, err := getIBC(amount ,denom[4:]) 
+        return resolvedCoin{
+            Display: toDisplay(amount, config.Chain.Exponent), Symbol: config.Chain.DisplayName, Name: config.Chain.DisplayName,
+            Asset: PricedAsset{Denom: denom, Exponent: config.Chain.Exponent, CoinGeckoID: config.Chain.CoinGeckoID},
+        }, true
+    } else if strings.HasPrefix(denom, "ibc/") {
+        trace, chain, err := getIBC(denom[4:])
         if err != nil {
-            return "Unknown IBC"
+            log.Println(color.YellowString("Failed to resolve IBC denom: %v", err))
+            return resolvedCoin{}, false
         }
-        var price float64
-        for i := range config.OtherChains {
-            chain := &config.OtherChains[i]
-            if chain.DisplayName == denom {
-                // Only query for data we need, and start auto refreshing the data
-                // Sleeps for a random amount of time, to wait for the response
-                // This is needed to prevent overloading the CoinGecko API
-                if !chain.CoinGeckoData.Active {
-                    chain.CoinGeckoData.Active = true
-                    url := "https://api.coingecko.com/api/v3/coins/" + chain.CoinGeckoData.ID
-                    // Random amount of time to stagger the messages, prevent all the API Requests from hitting at once.
-                    time.Sleep(time.Duration(rand.Intn(60-10+1)+10) * time.Second)
-                    go autoRefresh(url, &chain.CoinGeckoData.Data)
-                    // Wait for the data query
-                    time.Sleep(10 * time.Second)
-                }
-                price = *chain.CoinGeckoData.Price
-            }
+        name := chain.DisplayName
+        if config.Config.MessagesConfig.ShowDenomPath && trace.Path != "" {
+            name += " via " + trace.Path
         }
-        if price == 0 {
-            return formatter.Sprintf("%.2f %s (%s %s)", amount, denom,"?", config.Currency)
+        return resolvedCoin{
+            Display: toDisplay(amount, chain.Exponent), Symbol: chain.DisplayName, Name: name,
+            Asset: PricedAsset{Denom: trace.BaseDenom, Exponent: chain.Exponent, CoinGeckoID: chain.CoinGeckoID},
+        }, true
+    } else if asset, ok := findAsset(denom); ok {
+        return resolvedCoin{
+            Display: toDisplay(amount, asset.Exponent), Symbol: asset.DisplayName, Name: asset.DisplayName,
+            Asset: PricedAsset{Denom: denom, Exponent: asset.Exponent, CoinGeckoID: asset.CoinGeckoID},
+        }, true
+    }
+    return resolvedCoin{}, false
+}
+
+// Returns the amount of a coin in its display denom, the display name of the denom and its price in the currency
+// The price is 0 if it isn't known yet, returns false if the denom isn't known
+func coinValue(coin string, currency string) (*big.Rat, string, Quote, bool) {
+    resolved, ok := resolveCoin(coin)
+    if !ok {
+        return nil, "", Quote{}, false
+    }
+    return resolved.Display, resolved.Name, assetQuote(resolved.Asset, currency), true
+}
+
+// Returns the value of a list of coins in the currency, summed over the coins with a known price
+// Returns false if none of the coins has a known price, and if any of the prices used is stale
+func amountValue(msg string, currency string) (*big.Rat, bool, bool) {
+    total := new(big.Rat)
+    known, stale := false, false
+    for _, coin := range splitCoins(msg) {
+        display, _, quote, ok := coinValue(coin, currency)
+        if !ok || quote.Price == 0 {
+            continue
         }
-        return formatter.Sprintf("%.2f %s (%.2f %s)", amount, denom,(price * amount), config.Currency)
-    } else {
-        return "Unknown IBC"
+        total.Add(total, fiatValue(display, quote.Price))
+        known = true
+        stale = stale || quote.Stale
+    }
+    return total, known, stale
+}
+
+// Returns the sources of the prices of a list of coins, without duplicates
+func amountSources(msg string) []string {
+    var sources []string
+    for _, coin := range splitCoins(msg) {
+        _, _, quote, ok := coinValue(coin, "")
+        if ok && quote.Source != "" && !slices.Contains(sources, quote.Source) {
+            sources = append(sources, quote.Source)
+        }
+    }
+    return sources
+}
+
+// Converts a coin to the formatted amount in the currency
+// E.G. 1000000000nund becomes 1.00 FUND (0.03 USD), or 1.00 FUND (≈ 0.03 USD, 2h old) with a stale price
+// and 1.00 FUND (≈ ? USD) without a price
+func coinToAmount(coin string, currency string) string {
+    display, denom, quote, ok := coinValue(coin, currency)
+    if !ok {
+        return "Unknown Denom"
+    }
+    if quote.Price == 0 {
+        return fmt.Sprintf("%s %s (≈ %s %s)", formatDecimal(display), denom,"?", currencyName(currency))
+    }
+    if quote.Stale {
+        return fmt.Sprintf("%s %s (≈ %s %s, %s old)", formatDecimal(display), denom, formatDecimal(fiatValue(display, quote.Price)),
+            currencyName(currency), formatAge(time.Since(quote.FetchedAt)))
     }
+    return fmt.Sprintf("%s %s (%s %s)", formatDecimal(display), denom, formatDecimal(fiatValue(display, quote.Price)), currencyName(currency))
 }
-// Removes any MD incompatible charactres from a string
-func removeForbiddenChars(msg string) string {
-	msg = regexp.MustCompile(`[\[\]\(\)*]`).ReplaceAllString(msg, "")
-    return msg
+
+// Formats the age of a price, E.G. 45m, 2h or 3d
+func formatAge(age time.Duration) string {
+    switch {
+    case age < time.Hour:
+        return fmt.Sprintf("%dm", int(age.Minutes()))
+    case age < 48 * time.Hour:
+        return fmt.Sprintf("%dh", int(age.Hours()))
+    default:
+        return fmt.Sprintf("%dd", int(age.Hours() / 24))
+    }
 }
 
+// Converts the denom to the formatted amount in the currency, the default currency if none is given
+// E.G. 1000000000nund becomes 1.00 FUND (0.03 USD). Every coin of a list of coins is formatted, followed
+// by their total value, E.G. 1.00 FUND (0.03 USD), 1.00 ATOM (8.00 USD), total 8.03 USD
+func denomToAmount(msg string, currency string) string {
+    coins := splitCoins(msg)
+    if len(coins) < 2 {
+        return coinToAmount(msg, currency)
+    }
+    var amounts []string
+    for _, coin := range coins {
+        amounts = append(amounts, coinToAmount(coin, currency))
+    }
+    str := strings.Join(amounts, ", ")
+    if total, ok, stale := amountValue(msg, currency); ok && stale {
+        str += fmt.Sprintf(", total ≈ %s %s", formatDecimal(total), currencyName(currency))
+    } else if ok {
+        str += fmt.Sprintf(", total %s %s", formatDecimal(total), currencyName(currency))
+    }
+    return str
+}
 func ensureTrailingSlash(str *string) {
     if !strings.HasSuffix(*str, "/") {
         *str += "/" 
@@ -0,0 +1,101 @@
+package main
+
+import (
+    "encoding/binary"
+    "os"
+    "testing"
+)
+
+// Encodes a length delimited protobuf field
+func protoBytes(field uint64, value []byte) []byte {
+    msg := binary.AppendUvarint(nil, field << 3 | 2)
+    msg = binary.AppendUvarint(msg, uint64(len(value)))
+    return append(msg, value...)
+}
+
+func TestProtoField(t *testing.T) {
+    varint := binary.AppendUvarint(binary.AppendUvarint(nil, 3 << 3 | 0), 300)
+    fixed64 := append(binary.AppendUvarint(nil, 4 << 3 | 1), make([]byte, 8)...)
+    fixed32 := append(binary.AppendUvarint(nil, 5 << 3 | 5), make([]byte, 4)...)
+    memo := protoBytes(2, []byte("Debug123"))
+    concat := func(parts ...[]byte) []byte {
+        var msg []byte
+        for _, part := range parts {
+            msg = append(msg, part...)
+        }
+        return msg
+    }
+    tests := []struct {
+        name  string
+        msg   []byte
+        field uint64
+        want  string
+        found bool
+    }{
+        {name: "only field", msg: memo, field: 2, want: "Debug123", found: true},
+        {name: "after other fields", msg: concat(protoBytes(1, []byte("msgs")), varint, fixed64, fixed32, memo), field: 2, want: "Debug123", found: true},
+        {name: "first of repeated fields", msg: concat(memo, protoBytes(2, []byte("second"))), field: 2, want: "Debug123", found: true},
+        {name: "empty value", msg: protoBytes(2, nil), field: 2, want: "", found: true},
+        {name: "missing field", msg: concat(protoBytes(1, []byte("msgs")), varint), field: 2},
+        {name: "empty message", field: 2},
+        {name: "length beyond the message", msg: memo[:len(memo)-1], field: 2},
+        {name: "truncated varint", msg: []byte{3 << 3 | 0, 0x80}, field: 2},
+        {name: "truncated fixed64", msg: fixed64[:5], field: 2},
+        {name: "unsupported wire type", msg: concat([]byte{2 << 3 | 3}, memo), field: 2},
+    }
+    for _, tt := range tests {
+        value, found := protoField(tt.msg, tt.field)
+        if found != tt.found || string(value) != tt.want {
+            t.Errorf("%s: protoField = %q, %v, want %q, %v", tt.name, value, found, tt.want, tt.found)
+        }
+    }
+}
+
+func TestTxMemo(t *testing.T) {
+    useTestChains(t)
+    useTestResponses(t, map[string]string{
+        "https://rest.unification.io/cosmos/tx/v1beta1/txs/ABC": `{"tx":{"body":{"memo":"from rest"}}}`,
+    })
+    tx := func(body []byte) []byte {
+        return append(protoBytes(1, body), protoBytes(2, []byte("auth info"))...)
+    }
+    tests := []struct {
+        name string
+        tx   []byte
+        hash string
+        want string
+    }{
+        {name: "memo", tx: tx(append(protoBytes(1, []byte("msg")), protoBytes(2, []byte("Debug123"))...)), want: "Debug123"},
+        {name: "no memo", tx: tx(protoBytes(1, []byte("msg"))), want: ""},
+        {name: "packet forward memo", tx: tx(protoBytes(2, []byte(`{"forward":{"receiver":"cosmos1abc","channel":"channel-0"}}`))), want: ""},
+        {name: "undecodable tx falls back to the rest endpoint", tx: []byte{0xff}, hash: "ABC", want: "from rest"},
+        {name: "no tx falls back to the rest endpoint", hash: "ABC", want: "from rest"},
+        {name: "no tx and no hash", want: ""},
+    }
+    for _, tt := range tests {
+        var res WebsocketResponse
+        res.Result.Data.Value.TxResult.Tx = tt.tx
+        if tt.hash != "" {
+            res.Result.Events.TxHash = []string{tt.hash}
+        }
+        if got := txMemo(res); got != tt.want {
+            t.Errorf("%s: txMemo = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+// The memo of a websocket response recorded from the chain
+func TestTxMemoOfResponse(t *testing.T) {
+    data, err := os.ReadFile("../debug/jsonoutputs/transfer_with_memo.json")
+    if err != nil {
+        t.Skip(err)
+    }
+    res, err := parseWebsocketResponse(data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    useTestResponses(t, nil)
+    if got := txMemo(res); got != "Debug123" {
+        t.Errorf("txMemo = %q, want Debug123", got)
+    }
+}
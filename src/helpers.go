@@ -2,98 +2,169 @@ package main
 
 import (
 	"log"
-	"strconv"
 	"strings"
     "fmt"
-    "math"
-    "errors"
+    "math/big"
+    "regexp"
 
 	"github.com/fatih/color"
 )
 
-// Splits and amount like 1000nund, and returns 1000 and nund
-func splitAmountDenom(amount string) (float64, string){
-    var index int
-    var amnt string
-    for i, c := range amount{
-        if _, err := strconv.Atoi(string(c)); err == nil {
-            amnt += string(c)
-            index = i
-        } else {
-            break
-        }           
-    }
-    if amount == "" {
-        return 0, "UnknownDenom"
-    }
-    denom := amount[index+1:]
-    floatAmnt, _ := strconv.ParseFloat(amnt, 64)
-    return floatAmnt, denom 
-}
 // Checks if the message is allowed to send based on the whitelist/blacklist rules defined
-// TODO: use regex to ensure the string is clean for query (remove **, urls, etc)
+// The rules are matched against the fields of the message, not its text. Allowed messages also have to match the
+// filter expression of [expressions]
 func isAllowedMessage (res MessageResponse) bool {
-    switch res.Type.Filter {
-    case "blacklist":
-        for _, str := range res.Type.WhiteBlackList {
-            if strings.Contains(res.Message, str) {
-                logMsg := fmt.Sprintf("Filtered Message! Message of type %s contained blacklisted item: %s",res.TypeName,str)
-                log.Println(color.YellowString(logMsg))
+    allowed := isAllowedFields(res.Type, filterRules[res.TypeName], "Message of type " + res.TypeName, func() map[string][]string {
+        return messageFields(res)
+    })
+    if allowed && filterExpression != nil && !filterExpression.Matches(res) {
+        log.Println(color.YellowString(fmt.Sprintf("Filtered Message! Message of type %s did not match the filter expression", res.TypeName)))
+        return false
+    }
+    return allowed
+}
+// Checks if the message action is allowed based on the whitelist/blacklist rules defined, used by the Other
+// message type to filter on the action before the message is made
+func isAllowedAction(cfg MessageConfig, action string) bool {
+    return isAllowedFields(cfg, filterRules["Other"], "Action " + action, func() map[string][]string {
+        return map[string][]string{"action": {action}, "name": {actionName(action)}, "module": {actionModule(action)}}
+    })
+}
+// Checks the fields against the whitelist/blacklist rules, the fields are only collected when there are rules
+func isAllowedFields(cfg MessageConfig, rules []FilterRule, subject string, fields func() map[string][]string) bool {
+    if cfg.Filter != "blacklist" && cfg.Filter != "whitelist" {
+        return true
+    }
+    values := fields()
+    for _, rule := range rules {
+        if rule.matches(values) {
+            if cfg.Filter == "blacklist" {
+                log.Println(color.YellowString(fmt.Sprintf("Filtered Message! %s matched blacklisted item: %s", subject, rule.Text)))
                 return false
             }
+            return true
+        }
+    }
+    if cfg.Filter == "whitelist" {
+        log.Println(color.YellowString(fmt.Sprintf("Filtered Message! %s did not match any whitelisted items", subject)))
+        return false
+    }
+    return true
+}
+// Version of a message actions' package, E.G. v1beta1
+var actionVersion = regexp.MustCompile(`^v[0-9]`)
+
+// Returns the module of a message action, E.G. /cosmos.gov.v1beta1.MsgVote becomes gov
+func actionModule(action string) string {
+    parts := strings.Split(strings.TrimPrefix(action, "/"), ".")
+    for i, part := range parts {
+        // The module comes before the version
+        if i > 0 && actionVersion.MatchString(part) {
+            return parts[i-1]
         }
+    }
+    return "unknown"
+}
+// Returns the name of a message action, E.G. /cosmos.gov.v1beta1.MsgVote becomes MsgVote
+func actionName(action string) string {
+    return action[strings.LastIndex(action, ".")+1:]
+}
+// Checks if the amount meets the thresholds of the message type, when the message type filters on amounts
+// The currency threshold is checked on the summed value of a list of coins, and the denom thresholds on the amount
+// of their denom. The thresholds are combined with the threshold mode, and thresholds which can't be checked are
+// skipped: the currency threshold when the value is unknown or stale, and in or mode denom thresholds of denoms not in
+// the amount, which fail in and mode.
+// Without a threshold to check, the unknown price policy of the message type decides
+func isAllowedAmount(res MessageResponse) bool {
+    msg := res.Amount
+    if !res.Type.AmountFilter {
         return true
-    case "whitelist":
-        for _, str := range res.Type.WhiteBlackList {
-            if strings.Contains(res.Message, str) {
-                return true
+    }
+    var results []bool
+    var failed []string
+    if res.Type.Threshold > 0 || len(res.Type.DenomThresholds) == 0 {
+        if currencyAmount, ok, stale := amountValue(msg, ""); ok && !stale {
+            met := currencyAmount.Cmp(new(big.Rat).SetFloat64(res.Type.Threshold)) >= 0
+            results = append(results, met)
+            if !met {
+                failed = append(failed, fmt.Sprintf("%.0f %s", res.Type.Threshold, currencyName("")))
             }
         }
-        logMsg := fmt.Sprintf("Filtered Message! Message of type %s did not contain any whitelisted items",res.TypeName)
-        log.Println(color.YellowString(logMsg))
-        return false
-    default:
-        return true
     }
-}
-// TODO Allow this function to be used with other chains.
-func isAllowedAmount(res MessageResponse, msg string) bool {
-    amount, denom := splitAmountDenom(msg)
-    switch res.Type.AmountFilter {
-    case true:
-        if denom == config.Chain.Denom {
-            exp, _ := strconv.ParseFloat("1" + strings.Repeat("0",config.Chain.Exponent), 64)
-            amt := math.Round((amount/exp)*100)/100
-            currencyAmount := amt * *config.Chain.CoinGeckoData.Price
-            if currencyAmount < res.Type.Threshold {
-                logMsg := fmt.Sprintf("Filtered Message! Message of type %s did not meet the currency threshold of: %.0f %s",res.TypeName,res.Type.Threshold, config.Currency)
-                log.Println(color.YellowString(logMsg))
-                return false
+    amounts := denomAmounts(msg)
+    for name, threshold := range res.Type.DenomThresholds {
+        amount, ok := amounts[strings.ToLower(name)]
+        // Every threshold has to be met in and mode, which a denom not in the amount can't
+        if !ok && res.Type.ThresholdMode == "and" {
+            results = append(results, false)
+            failed = append(failed, fmt.Sprintf("%.0f %s (not in the amount)", threshold, name))
+            continue
+        }
+        if !ok {
+            continue
+        }
+        met := amount.Cmp(new(big.Rat).SetFloat64(threshold)) >= 0
+        results = append(results, met)
+        if !met {
+            failed = append(failed, fmt.Sprintf("%.0f %s", threshold, name))
+        }
+    }
+    if len(results) > 0 {
+        allowed := results[0]
+        for _, met := range results[1:] {
+            if res.Type.ThresholdMode == "and" {
+                allowed = allowed && met
             } else {
-                return true
+                allowed = allowed || met
             }
-        } else {
-            logMsg := fmt.Sprintf("Filtered Message! Message of type %s is an unknown currency conversion, so could not meet the currency threshold",res.TypeName)
+        }
+        if !allowed {
+            logMsg := fmt.Sprintf("Filtered Message! Message of type %s did not meet the thresholds of: %s",res.TypeName, strings.Join(failed, ", "))
+            log.Println(color.YellowString(logMsg))
+        }
+        return allowed
+    }
+    switch res.Type.UnknownPrice {
+    case "pass":
+        return true
+    case "native":
+        if nativeAmount(msg).Cmp(new(big.Rat).SetFloat64(res.Type.NativeThreshold)) < 0 {
+            logMsg := fmt.Sprintf("Filtered Message! Message of type %s has an unknown value, and did not meet the native threshold of: %.0f %s",res.TypeName,res.Type.NativeThreshold, config.Chain.DisplayName)
             log.Println(color.YellowString(logMsg))
             return false
         }
-    case false:
         return true
+    default:
+        logMsg := fmt.Sprintf("Filtered Message! Message of type %s is an unknown currency conversion, so could not meet the currency threshold",res.TypeName)
+        log.Println(color.YellowString(logMsg))
+        return false
     }
-    return true
 }
-// TODO Have this function read asset data from the chains as well, instead of just the primary denoms'
-func getIBC(amount float64, denom string) (float64,string, error) {
-    var ibc IBCResponse
-    url := config.Connections.Rest + "/ibc/apps/transfer/v1/denom_traces/" + denom
-    getData(url, &ibc)
-    for _, chain := range(config.OtherChains) {
-        if chain.Denom == ibc.DenomTrace.BaseDenom {
-            display := chain.DisplayName
-            exp, _ := strconv.ParseFloat("1" + strings.Repeat("0",chain.Exponent), 64)
-            amount = math.Round((amount/exp)*100)/100
-            return amount, strings.ToUpper(display), nil
+// Returns the amounts of a list of coins in their display denom, keyed by both the lower case display name
+// and base denom of the coins, E.G. atom and uatom. Coins of the same asset over different IBC paths are summed
+func denomAmounts(msg string) map[string]*big.Rat {
+    amounts := map[string]*big.Rat{}
+    for _, coin := range splitCoins(msg) {
+        resolved, ok := resolveCoin(coin)
+        if !ok {
+            continue
+        }
+        for _, key := range []string{strings.ToLower(resolved.Symbol), strings.ToLower(resolved.Asset.Denom)} {
+            if amounts[key] == nil {
+                amounts[key] = new(big.Rat)
+            }
+            amounts[key].Add(amounts[key], resolved.Display)
+        }
+    }
+    return amounts
+}
+// Returns the amount of the chains' coin in a list of coins, in its display denom
+func nativeAmount(msg string) *big.Rat {
+    total := new(big.Int)
+    for _, coin := range splitCoins(msg) {
+        if amount, denom := splitAmountDenom(coin); denom == config.Chain.Denom {
+            total.Add(total, amount)
         }
     }
-    return 0, "", errors.New("Data not available")
+    return toDisplay(total, config.Chain.Exponent)
 }
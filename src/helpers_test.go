@@ -0,0 +1,46 @@
+package main
+
+import (
+    "testing"
+)
+
+func TestIsAllowedAmount(t *testing.T) {
+    useTestChains(t)
+    useTestResponses(t, nil)
+    // 100,100 FUND
+    amount := "100100000000000nund"
+    tests := []struct {
+        name    string
+        cfg     MessageConfig
+        allowed bool
+    }{
+        {name: "amount filter disabled", cfg: MessageConfig{DenomThresholds: map[string]float64{"FUND": 1e9}}, allowed: true},
+        {name: "denom threshold met", cfg: MessageConfig{AmountFilter: true, DenomThresholds: map[string]float64{"FUND": 100}}, allowed: true},
+        {name: "denom threshold by base denom", cfg: MessageConfig{AmountFilter: true, DenomThresholds: map[string]float64{"nund": 100}}, allowed: true},
+        {name: "denom threshold not met", cfg: MessageConfig{AmountFilter: true, DenomThresholds: map[string]float64{"FUND": 200000}}, allowed: false},
+        // A denom not in the amount fails and mode, and is skipped in or mode
+        {name: "and mode with a missing denom", cfg: MessageConfig{AmountFilter: true, ThresholdMode: "and",
+            DenomThresholds: map[string]float64{"FUND": 100, "ATOM": 5}}, allowed: false},
+        {name: "or mode with a missing denom", cfg: MessageConfig{AmountFilter: true, ThresholdMode: "or",
+            DenomThresholds: map[string]float64{"FUND": 100, "ATOM": 5}}, allowed: true},
+        {name: "and mode with one threshold not met", cfg: MessageConfig{AmountFilter: true, ThresholdMode: "and",
+            DenomThresholds: map[string]float64{"FUND": 100, "nund": 200000}}, allowed: false},
+        {name: "or mode with one threshold met", cfg: MessageConfig{AmountFilter: true, ThresholdMode: "or",
+            DenomThresholds: map[string]float64{"FUND": 100, "nund": 200000}}, allowed: true},
+        // Without any threshold to compare, the unknown price policy decides
+        {name: "unknown price passes", cfg: MessageConfig{AmountFilter: true, UnknownPrice: "pass",
+            DenomThresholds: map[string]float64{"ATOM": 5}}, allowed: true},
+        {name: "unknown price blocks", cfg: MessageConfig{AmountFilter: true, UnknownPrice: "block",
+            DenomThresholds: map[string]float64{"ATOM": 5}}, allowed: false},
+        {name: "unknown price meets the native threshold", cfg: MessageConfig{AmountFilter: true, UnknownPrice: "native", NativeThreshold: 100,
+            DenomThresholds: map[string]float64{"ATOM": 5}}, allowed: true},
+        {name: "unknown price misses the native threshold", cfg: MessageConfig{AmountFilter: true, UnknownPrice: "native", NativeThreshold: 1e6,
+            DenomThresholds: map[string]float64{"ATOM": 5}}, allowed: false},
+    }
+    for _, tt := range tests {
+        res := MessageResponse{TypeName: "Test", Type: tt.cfg, Amount: amount}
+        if got := isAllowedAmount(res); got != tt.allowed {
+            t.Errorf("%s: allowed = %v, want %v", tt.name, got, tt.allowed)
+        }
+    }
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// How long to remember outbound packets for, packets older than this are assumed relayed
+const packetExpiry = 7 * 24 * time.Hour
+
+// An outbound IBC transfer, and the messages that were sent announcing it
+type OutboundPacket struct {
+    Sender    string
+    Recipient string
+    Amount    string
+    TxHash    string
+    Time      time.Time
+    Sent      []SentMessage
+}
+
+// A message sent to a client's channel, which can later be edited
+type SentMessage struct {
+    Client  string
+    Chat    string
+    ID      string
+    Doc     Document
+    Edit    []Field
+}
+
+// Outbound packets seen by the websocket, keyed by their source channel and sequence
+var packets = struct {
+    sync.Mutex
+    m map[string]*OutboundPacket
+}{m: map[string]*OutboundPacket{}}
+
+// How long to wait before querying a channel whose counterparty couldn't be resolved again
+const channelRetry = 15 * time.Minute
+
+// Counterparty chain IDs of the IBC channels, keyed by the rest URL, port and channel
+var channels = struct {
+    sync.Mutex
+    m map[string]channelState
+}{m: map[string]channelState{}}
+
+// The counterparty chain ID of a channel, or the error resolving it and when to try again
+type channelState struct {
+    ChainID string
+    Err     error
+    Retry   time.Time
+}
+
+// Returns the key used to correlate a packet, E.G. channel-0/198
+func packetKey(channel string, sequence string) string {
+    return channel + "/" + sequence
+}
+
+// Remembers an outbound packet so it can be correlated to its acknowledgement or timeout
+// Expired packets are dropped at the same time
+func recordPacket(key string, packet OutboundPacket) {
+    packets.Lock()
+    defer packets.Unlock()
+    for k, p := range packets.m {
+        if time.Since(p.Time) > packetExpiry {
+            delete(packets.m, k)
+        }
+    }
+    packet.Time = time.Now()
+    packets.m[key] = &packet
+}
+
+// Remembers a message that was sent for an outbound packet, so it can be edited if the transfer fails
+func recordSent(key string, sent SentMessage) {
+    packets.Lock()
+    defer packets.Unlock()
+    if packet, ok := packets.m[key]; ok {
+        packet.Sent = append(packet.Sent, sent)
+    }
+}
+
+// Returns and forgets the outbound packet for the key
+func takePacket(key string) (OutboundPacket, bool) {
+    packets.Lock()
+    defer packets.Unlock()
+    packet, ok := packets.m[key]
+    if !ok {
+        return OutboundPacket{}, false
+    }
+    delete(packets.m, key)
+    return *packet, true
+}
+
+// Converts the denom of a packet into the denom used on this chain
+// E.G. nund stays nund, while transfer/channel-0/uatom becomes ibc/27394...
+func packetDenom(denom string) string {
+    if !strings.Contains(denom, "/") {
+        return denom
+    }
+    return fmt.Sprintf("ibc/%X", sha256.Sum256([]byte(denom)))
+}
+
+// Acknowledgements are formatted like result:"\001" or error:"ABCI code: 6: error handling packet..."
+func isErrorAck(ack string) bool {
+    return strings.HasPrefix(strings.TrimSpace(ack), "error")
+}
+
+// Cleans an error acknowledgement for display, E.G. error:"ABCI code: 6: error handling packet" becomes
+// ABCI code: 6: error handling packet
+func ackReason(ack string) string {
+    reason := strings.TrimPrefix(strings.TrimSpace(ack), "error:")
+    return strings.Trim(reason, `"`)
+}
+
+// Fills the message with the refunded transfers, and sets the edits for their original messages
+// Returns false if every refund was filtered
+func mkRefundMessage(msg *MessageResponse, refunds []OutboundPacket, reasons []string, hash string) bool {
+    msg.Doc.Title = "↩️ IBC Transfer Refunded ↩️"
+    var data []map[string]string
+    var amounts []string
+    for i, packet := range refunds {
+        // Each refund is filtered on its own amount
+        refund := *msg
+        refund.Amount = packet.Amount
+        if !isAllowedAmount(refund) {
+            continue
+        }
+        amounts = append(amounts, packet.Amount)
+        data = append(data, map[string]string{
+            "sender": packet.Sender,
+            "recipient": packet.Recipient,
+            "amount": packet.Amount,
+            "reason": reasons[i],
+            "original": packet.TxHash,
+        })
+        msg.Doc.AddGroup("Sender", accountText(packet.Sender))
+        if packet.Recipient != "" {
+            msg.Doc.Add("Recipient", accountText(packet.Recipient))
+        }
+        msg.Doc.Add("Amount", amountText(hash, packet.Amount))
+        msg.Doc.Add("Reason", plain(reasons[i]))
+        if packet.TxHash != "" {
+            msg.Doc.Add("Original TX", hashText(packet.TxHash))
+        }
+        for _, sent := range packet.Sent {
+            sent.Edit = []Field{
+                {Name: "❌ Refunded", Lines: [][]Text{{plain(reasons[i])}}, Break: true},
+                {Name: "Refund TX", Lines: [][]Text{{hashText(hash)}}},
+            }
+            msg.Refunds = append(msg.Refunds, sent)
+        }
+    }
+    msg.Data = MessageData{"refunds": data}
+    msg.Amount = strings.Join(amounts, ",")
+    return len(data) > 0
+}
+
+// Returns the chain ID of the chain on the other end of a channel, as seen by the chain at the rest URL
+// Channels don't change their counterparty, so the results are cached. Failures are logged and cached
+// as well, and only queried again after channelRetry
+func getChannelChainID(rest string, port string, channel string) (string, error) {
+    key := rest + port + "/" + channel
+    channels.Lock()
+    state, ok := channels.m[key]
+    channels.Unlock()
+    if ok && (state.Err == nil || time.Now().Before(state.Retry)) {
+        return state.ChainID, state.Err
+    }
+    id, err := queryChannelChainID(rest, port, channel)
+    if err != nil && err != errOffline {
+        log.Println(color.YellowString("Failed to resolve IBC channel %s/%s: %v", port, channel, err))
+    }
+    channels.Lock()
+    channels.m[key] = channelState{ChainID: id, Err: err, Retry: time.Now().Add(channelRetry)}
+    channels.Unlock()
+    return id, err
+}
+
+// Queries the chain ID of the chain on the other end of a channel
+func queryChannelChainID(rest string, port string, channel string) (string, error) {
+    var res ClientStateResponse
+    url := rest + "ibc/core/channel/v1/channels/" + channel + "/ports/" + port + "/client_state"
+    if err := getData(url, &res); err != nil {
+        return "", err
+    }
+    id := res.IdentifiedClientState.ClientState.ChainID
+    if id == "" {
+        return "", errors.New("No client state available for: " + channel)
+    }
+    return id, nil
+}
+
+// Returns the registry data for the chain with the given chain ID
+func getChainByID(id string) (ChainData, bool) {
+    if id != "" && id == config.Chain.ChainID {
+        chain := config.Chain
+        chain.Rest = config.Connections.Rest
+        return chain, true
+    }
+    for _, chain := range config.OtherChains {
+        if chain.ChainID == id {
+            return chain, true
+        }
+    }
+    return ChainData{}, false
+}
+
+// Returns a link to the explorer of the chain with the given chain ID, or just the chain ID if it isn't in the registry
+func chainText(id string) Text {
+    chain, ok := getChainByID(id)
+    if !ok {
+        return plain(id)
+    }
+    return Text{Text: chain.PrettyName, URL: chainURL(chain)}
+}
+
+// Returns the route of an IBC transfer through a channel of this chain
+// E.G. Unification → Osmosis (channel-0), or Osmosis → Unification (channel-0) when incoming
+// Transfers forwarded by the memo continue the route, and the final hop of the memo is returned as well
+func mkRoute(channel string, incoming bool, memo string) ([]Text, MemoRoute) {
+    counterparty := plain(channel)
+    id, err := getChannelChainID(config.Connections.Rest, "transfer", channel)
+    if err == nil {
+        counterparty = chainText(id)
+    }
+    home := plain(config.Chain.PrettyName)
+    var route []Text
+    var hops MemoRoute
+    if incoming {
+        route = []Text{counterparty, plain(" → "), home, plain(" (" + channel + ")")}
+        hops = decodeMemo(config.Connections.Rest, memo)
+    } else {
+        route = []Text{home, plain(" → "), counterparty, plain(" (" + channel + ")")}
+        if chain, ok := getChainByID(id); ok && chain.Rest != "" {
+            hops = decodeMemo(chain.Rest, memo)
+        } else {
+            hops = decodeMemo("", memo)
+        }
+    }
+    for _, hop := range hops.Hops {
+        route = append(route, plain(" → "))
+        route = append(route, hop...)
+    }
+    return route, hops
+}
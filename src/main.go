@@ -5,8 +5,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
-    "regexp"
-	"strings"
+	"strconv"
 	"time"
     "fmt"
 
@@ -27,21 +26,32 @@ var (
     // Flags
     configpath string
     initconfig bool
+    testfilter string
+    jsonoutputs string
 )
 
-func init(){
+// Parses the flags and the config, run by main rather than init so the package can be tested without a config
+func parseFlags(){
     flag.StringVar(&configpath,"config", ".", "Directory containing your config.toml")
     flag.BoolVar(&initconfig,"init", false, "Creates a predefined config.toml file, if the config path is not set, defaults to the CWD")
+    flag.StringVar(&testfilter,"test-filter", "", "Evaluates a filter expression offline against the websocket responses in the json outputs directory, then exits")
+    flag.StringVar(&jsonoutputs,"json-outputs", "", "Directory containing the websocket responses used by -test-filter, defaults to debug/jsonoutputs of the repository")
     flag.Parse()
     if initconfig {
         initConfig(configpath) 
         os.Exit(1)
     }
+    if testfilter != "" {
+        config.parseOfflineConfig(configpath)
+        testFilter(testfilter, fetchOffline)
+        os.Exit(0)
+    }
     config.parseConfig(configpath)
 }
 
 // Start the telegram bot and listen for messages from the resp channel
 func main(){
+    parseFlags()
     var err error
     interrupt := make(chan os.Signal, 1) 
     signal.Notify(interrupt, os.Interrupt) 
@@ -73,49 +83,105 @@ func main(){
     // Connect to the websocket
     go Connect(resp, restart)
 
-    // AutoRefresh coin gecko and validator set data
-    cgURL := "https://api.coingecko.com/api/v3/coins/" + config.Chain.CoinGeckoData.ID
+    // Refresh the prices, starting with the chains' coin, and the validator set data
+    getPrice(config.Chain.CoinGeckoID, "")
+    go priceService()
     valURL := config.Connections.Rest + "cosmos/staking/v1beta1/validators?pagination.limit=100000"
-    go autoRefresh(cgURL,&config.Chain.CoinGeckoData.Data)
-    go autoRefresh(valURL,&vals)
+    go autoRefresh(valURL,&vals,indexValidators)
+
+    // Periodically report the supply of the chains' coin
+    if config.Config.SupplyConfig.Enabled && config.Config.SupplyConfig.ReportInterval > 0 {
+        go autoReport(resp)
+    }
+
+    // Announce moves of the price of the chains' coin
+    if config.Config.AlertsConfig.Price.Enabled {
+        go priceAlerts(resp)
+    }
 
     // Listen and serve
     go func(){
         for {
             select {
             case message := <- resp:
+                // Edit the original messages of refunded IBC transfers, those channels won't get a new message
+                edited := map[string]bool{}
+                if config.Config.MessagesConfig.IBCFailed.EditOriginal {
+                    for _, sent := range message.Refunds {
+                        if editMessage(sent) {
+                            edited[sent.Client + sent.Chat] = true
+                        }
+                    }
+                }
                 for _, client := range config.Config.ClientsConfig.Clients {
                     switch client {
                     case "telegram":
-                        tgMessage := strings.ReplaceAll(message.Message,"**","*")
+                        // Rendered once per currency of the chats
+                        rendered := map[string]string{}
+                        routed := routedChats(message, config.Config.ClientsConfig.TgChatIDs)
                         for _, chat := range config.Config.ClientsConfig.TgChatIDs {
+                            if edited[client + chat] || !routed[chat] {
+                                continue
+                            }
+                            doc := message.Doc
+                            doc.Currency = chatCurrency(chat)
+                            tgMessage, ok := rendered[doc.Currency]
+                            if !ok {
+                                var err error
+                                if tgMessage, err = telegramText(doc, nil); err != nil {
+                                    log.Println(color.YellowString("Could not render telegram message of type " + message.TypeName + ": " + err.Error()))
+                                    continue
+                                }
+                                rendered[doc.Currency] = tgMessage
+                            }
                             msg := telegram.NewMessageToChannel(chat, tgMessage)
-                            msg.ParseMode = telegram.ModeMarkdown
+                            msg.ParseMode = telegramParseMode()
                             msg.DisableWebPagePreview = true
-                            _, err := tgbot.Send(msg)
+                            sent, err := tgbot.Send(msg)
                             if err != nil {
                                 log.Println(color.YellowString("Could not sent telegram message, check your internet connection or ChatID", err))
                             } else {
                                 logMsg := fmt.Sprintf("Sent message of type %s to Telegram Channel: %s",message.TypeName, chat)
                                 log.Println(color.BlueString(logMsg))
+                                if message.Packet != "" {
+                                    recordSent(message.Packet, SentMessage{
+                                        Client: client, Chat: chat, ID: strconv.Itoa(sent.MessageID), Doc: doc,
+                                    })
+                                }
                             }
 
                         }
                     case "discord":
-                        // Define the regular expression pattern
-                        dscMessage := regexp.MustCompile(`\[(.*?)\]\((.*?)\)`).ReplaceAllString(message.Message, "**[$1]($2)**")
+                        // Rendered once per currency of the chats
+                        rendered := map[string]*discord.MessageEmbed{}
+                        routed := routedChats(message, config.Config.ClientsConfig.DscChatIDs)
                         for _, chat := range config.Config.ClientsConfig.DscChatIDs {
-                            embd := discord.MessageEmbed {
-                                Description: dscMessage, 
-                                Color: 5793266,
-                                Timestamp: fmt.Sprint(time.Now().Format(time.RFC3339)),
+                            if edited[client + chat] || !routed[chat] {
+                                continue
                             }
-                            _, err := dscbot.ChannelMessageSendEmbed(chat, &embd)
+                            doc := message.Doc
+                            doc.Currency = chatCurrency(chat)
+                            embd, ok := rendered[doc.Currency]
+                            if !ok {
+                                var err error
+                                if embd, err = discordEmbed(doc, nil); err != nil {
+                                    log.Println(color.YellowString("Could not render discord message of type " + message.TypeName + ": " + err.Error()))
+                                    continue
+                                }
+                                embd.Color = discordColor(message.TypeName, message.Type)
+                                rendered[doc.Currency] = embd
+                            }
+                            sent, err := dscbot.ChannelMessageSendEmbed(chat, embd)
                             if err != nil {
                                 log.Println(color.YellowString("Could not sent discord message, check your internet connection or ChatID", err))
                             } else {
                                 logMsg := fmt.Sprintf("Sent message of type %s to Discord Channel: %s",message.TypeName, chat)
                                 log.Println(color.BlueString(logMsg))
+                                if message.Packet != "" {
+                                    recordSent(message.Packet, SentMessage{
+                                        Client: client, Chat: chat, ID: sent.ID, Doc: doc,
+                                    })
+                                }
                             }
                         }
                     }
@@ -134,3 +200,79 @@ func main(){
         return
     }
 }
+
+// Appends the edit to a previously sent message, returns false if the message could not be edited
+func editMessage(sent SentMessage) bool {
+    switch sent.Client {
+    case "telegram":
+        id, err := strconv.Atoi(sent.ID)
+        if err != nil {
+            return false
+        }
+        text, err := telegramText(sent.Doc, sent.Edit)
+        if err != nil {
+            log.Println(color.YellowString("Could not render telegram message: " + err.Error()))
+            return false
+        }
+        edit := telegram.EditMessageTextConfig{
+            BaseEdit: telegram.BaseEdit{ChannelUsername: sent.Chat, MessageID: id},
+            Text: text,
+            ParseMode: telegramParseMode(),
+            DisableWebPagePreview: true,
+        }
+        if _, err := tgbot.Send(edit); err != nil {
+            log.Println(color.YellowString("Could not edit telegram message, check your internet connection or ChatID: %v", err))
+            return false
+        }
+    case "discord":
+        embd, err := discordEmbed(sent.Doc, sent.Edit)
+        if err != nil {
+            log.Println(color.YellowString("Could not render discord message: " + err.Error()))
+            return false
+        }
+        embd.Color = discordEditColor
+        if _, err := dscbot.ChannelMessageEditEmbed(sent.Chat, sent.ID, embd); err != nil {
+            log.Println(color.YellowString("Could not edit discord message, check your internet connection or ChatID: %v", err))
+            return false
+        }
+    default:
+        return false
+    }
+    log.Println(color.BlueString(fmt.Sprintf("Edited refunded IBC message in %s Channel: %s", sent.Client, sent.Chat)))
+    return true
+}
+
+// Returns the currency of the chat, the default currency if it has none
+func chatCurrency(chat string) string {
+    return currencyOrDefault(config.Config.ClientsConfig.ChatCurrencies[chat])
+}
+
+// Returns the markup of the configured telegram parse mode
+func telegramMarkup() Markup {
+    if config.Config.ClientsConfig.TgParseMode == "markdownv2" {
+        return telegramMarkdownV2{}
+    }
+    return telegramHTML{}
+}
+
+// Returns the configured telegram parse mode
+func telegramParseMode() string {
+    if config.Config.ClientsConfig.TgParseMode == "markdownv2" {
+        return telegram.ModeMarkdownV2
+    }
+    return telegram.ModeHTML
+}
+
+// Renders the document as a telegram message, with the edit appended
+// Telegram trims messages, so they are padded with an invisible character to keep them apart in the channel
+func telegramText(doc Document, edit []Field) (string, error) {
+    m := telegramMarkup()
+    text, err := doc.Render(m)
+    if err != nil {
+        return "", err
+    }
+    if len(edit) > 0 {
+        text += "\n" + renderFields(m, fieldsInCurrency(edit, doc.Currency))
+    }
+    return "\n‎" + text + "\n‎", nil
+}
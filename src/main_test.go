@@ -0,0 +1,51 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "testing"
+)
+
+// Sets up the home chain and two other chains, restored when the test ends
+func useTestChains(t *testing.T) {
+    saved := config
+    t.Cleanup(func() { config = saved })
+    explorers := []Explorer{explorerPresets["ping.pub"]}
+    config.Chain = ChainData{ChainName: "unification", ChainID: "FUND-MainNet-2", PrettyName: "Unification",
+        DisplayName: "FUND", Denom: "nund", Exponent: 9, Prefix: "und", ExplorerPath: "unification", Explorers: explorers}
+    config.Connections.Rest = "https://rest.unification.io/"
+    config.OtherChains = []ChainData{
+        {ChainName: "osmosis", ChainID: "osmosis-1", PrettyName: "Osmosis", DisplayName: "OSMO", Denom: "uosmo",
+            Exponent: 6, Prefix: "osmo", Rest: "https://rest.osmosis.zone/", ExplorerPath: "osmosis", Explorers: explorers},
+        {ChainName: "cosmoshub", ChainID: "cosmoshub-4", PrettyName: "Cosmos Hub", DisplayName: "ATOM", Denom: "uatom",
+            Exponent: 6, Prefix: "cosmos", Rest: "https://rest.cosmos.directory/cosmoshub/", ExplorerPath: "cosmos", Explorers: explorers},
+    }
+}
+
+// Answers the queries with the json responses keyed by their URL, other URLs fail. The cached channels are
+// cleared, and everything is restored when the test ends
+func useTestResponses(t *testing.T, responses map[string]string) {
+    saved := fetch
+    clearChannels := func() {
+        channels.Lock()
+        channels.m = map[string]channelState{}
+        channels.Unlock()
+    }
+    clearChannels()
+    t.Cleanup(func() {
+        fetch = saved
+        clearChannels()
+    })
+    fetch = func(url string, height string, container interface{}) error {
+        body, ok := responses[url]
+        if !ok {
+            return errors.New("no response for " + url)
+        }
+        return json.Unmarshal([]byte(body), container)
+    }
+}
+
+// Returns the client state response of a channel to a chain
+func clientState(chainID string) string {
+    return `{"identified_client_state":{"client_state":{"chain_id":"` + chainID + `"}}}`
+}
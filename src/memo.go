@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// ICS-20 memo used by the packet-forward middleware and IBC hooks
+type PacketMemo struct {
+    Forward *ForwardMemo `json:"forward"`
+    Wasm    *WasmMemo    `json:"wasm"`
+}
+type ForwardMemo struct {
+    Receiver string          `json:"receiver"`
+    Port     string          `json:"port"`
+    Channel  string          `json:"channel"`
+    // Older versions of the packet-forward middleware encode the next memo as a JSON string
+    Next     json.RawMessage `json:"next"`
+}
+type WasmMemo struct {
+    Contract string                     `json:"contract"`
+    Msg      map[string]json.RawMessage `json:"msg"`
+}
+// Crosschain swaps on Osmosis
+type OsmosisSwapMsg struct {
+    OutputDenom string `json:"output_denom"`
+    Receiver    string `json:"receiver"`
+}
+// Skip API swaps
+type SwapAndActionMsg struct {
+    UserSwap struct {
+        SwapExactAssetIn struct {
+            SwapVenueName string `json:"swap_venue_name"`
+        } `json:"swap_exact_asset_in"`
+    } `json:"user_swap"`
+    MinAsset struct {
+        Native Coin `json:"native"`
+    } `json:"min_asset"`
+    PostSwapAction struct {
+        Transfer *struct {
+            ToAddress string `json:"to_address"`
+        } `json:"transfer"`
+        IBCTransfer *struct {
+            IBCInfo struct {
+                SourceChannel string `json:"source_channel"`
+                Receiver      string `json:"receiver"`
+            } `json:"ibc_info"`
+        } `json:"ibc_transfer"`
+    } `json:"post_swap_action"`
+}
+
+// The hops of a transfer decoded from its memo, after the chain receiving the packet
+type MemoRoute struct {
+    Hops     [][]Text
+    Receiver string
+    Swap     string
+    Contract string
+}
+
+// Parses an ICS-20 memo, returns false if it isn't a forward or wasm memo
+func parsePacketMemo(memo []byte) (PacketMemo, bool) {
+    var pm PacketMemo
+    // The memo may be JSON encoded as a string
+    var str string
+    if err := json.Unmarshal(memo, &str); err == nil {
+        memo = []byte(str)
+    }
+    if err := json.Unmarshal(memo, &pm); err != nil {
+        return PacketMemo{}, false
+    }
+    return pm, pm.Forward != nil || pm.Wasm != nil
+}
+
+// Follows the forwards and contract calls of the memo, starting on the chain at the rest URL which receives the
+// packet. If the rest URL is empty, the chains along the route can't be resolved and only the channels are shown
+func decodeMemo(rest string, memo string) MemoRoute {
+    var route MemoRoute
+    pm, ok := parsePacketMemo([]byte(memo))
+    for ok {
+        if pm.Forward != nil {
+            hop := pm.Forward
+            route.Hops = append(route.Hops, mkHop(&rest, hop.Port, hop.Channel))
+            route.Receiver = hop.Receiver
+            pm, ok = parsePacketMemo(hop.Next)
+            continue
+        }
+        route.Contract = pm.Wasm.Contract
+        if raw, found := pm.Wasm.Msg["osmosis_swap"]; found {
+            var swap OsmosisSwapMsg
+            if err := json.Unmarshal(raw, &swap); err == nil {
+                route.Swap = "Swap to " + mkDenomName(swap.OutputDenom)
+                // Crosschain swaps may send the output to another chain, E.G. ibc:channel-0/cosmos1...
+                route.Receiver = swap.Receiver
+                if i := strings.LastIndex(swap.Receiver, "/"); i >= 0 {
+                    route.Receiver = swap.Receiver[i+1:]
+                }
+            }
+        } else if raw, found := pm.Wasm.Msg["swap_and_action"]; found {
+            var swap SwapAndActionMsg
+            if err := json.Unmarshal(raw, &swap); err == nil {
+                route.Swap = "Swap to " + mkDenomName(swap.MinAsset.Native.Denom)
+                if venue := swap.UserSwap.SwapExactAssetIn.SwapVenueName; venue != "" {
+                    route.Swap += " on " + venue
+                }
+                if swap.PostSwapAction.Transfer != nil {
+                    route.Receiver = swap.PostSwapAction.Transfer.ToAddress
+                } else if ibc := swap.PostSwapAction.IBCTransfer; ibc != nil {
+                    route.Hops = append(route.Hops, mkHop(&rest, "transfer", ibc.IBCInfo.SourceChannel))
+                    route.Receiver = ibc.IBCInfo.Receiver
+                }
+            }
+        } else {
+            // Unknown contract call, show the name of the message
+            var actions []string
+            for action := range pm.Wasm.Msg {
+                actions = append(actions, action)
+            }
+            sort.Strings(actions)
+            route.Swap = "Execute " + strings.Join(actions, ", ")
+        }
+        break
+    }
+    return route
+}
+
+// Resolves the chain at the other end of the channel, as seen by the chain at the rest URL
+// The rest URL is moved to the next chain, or emptied if the next chain is unknown
+func mkHop(rest *string, port string, channel string) []Text {
+    if port == "" {
+        port = "transfer"
+    }
+    if *rest == "" {
+        return []Text{plain(channel)}
+    }
+    id, err := getChannelChainID(*rest, port, channel)
+    if err != nil {
+        *rest = ""
+        return []Text{plain(channel)}
+    }
+    *rest = ""
+    if chain, ok := getChainByID(id); ok {
+        *rest = chain.Rest
+    }
+    return []Text{chainText(id), plain(" (" + channel + ")")}
+}
+
+// Adds the details of the final hop to the document
+func (route MemoRoute) AddFields(doc *Document) {
+    if route.Receiver != "" {
+        doc.Add("Final Recipient", accountText(route.Receiver))
+    }
+    if route.Swap != "" {
+        doc.Add("Action", plain(route.Swap))
+    }
+    if route.Contract != "" {
+        doc.Add("Contract", accountText(route.Contract))
+    }
+}
+
+// Returns the display name of a denom on any known chain, E.G. uatom becomes ATOM
+func mkDenomName(denom string) string {
+    if denom == config.Chain.Denom {
+        return config.Chain.DisplayName
+    }
+    for _, chain := range config.OtherChains {
+        if chain.Denom == denom {
+            return chain.DisplayName
+        }
+    }
+    if len(denom) > 16 {
+        return denom[:12] + "..."
+    }
+    return denom
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+    "testing"
+)
+
+func TestDecodeMemo(t *testing.T) {
+    useTestChains(t)
+    osmosis := "https://rest.osmosis.zone/"
+    useTestResponses(t, map[string]string{
+        osmosis + "ibc/core/channel/v1/channels/channel-0/ports/transfer/client_state": clientState("cosmoshub-4"),
+        osmosis + "ibc/core/channel/v1/channels/channel-9/ports/transfer/client_state": clientState("unknown-1"),
+    })
+    tests := []struct {
+        name     string
+        rest     string
+        memo     string
+        hops     []string
+        receiver string
+        swap     string
+        contract string
+    }{
+        {name: "text memo", memo: "hello"},
+        {name: "empty memo", memo: ""},
+        {name: "json without a forward or wasm", memo: `{"other":{}}`},
+        {
+            name: "forward without a rest url",
+            memo: `{"forward":{"receiver":"cosmos1abc","port":"transfer","channel":"channel-0"}}`,
+            hops: []string{"channel-0"},
+            receiver: "cosmos1abc",
+        },
+        {
+            name: "forward resolved on the receiving chain",
+            rest: osmosis,
+            memo: `{"forward":{"receiver":"cosmos1abc","port":"transfer","channel":"channel-0"}}`,
+            hops: []string{"Cosmos Hub (channel-0)"},
+            receiver: "cosmos1abc",
+        },
+        {
+            name: "forward without a port defaults to transfer",
+            rest: osmosis,
+            memo: `{"forward":{"receiver":"cosmos1abc","channel":"channel-0"}}`,
+            hops: []string{"Cosmos Hub (channel-0)"},
+            receiver: "cosmos1abc",
+        },
+        {
+            name: "forward to an unknown chain stops resolving the next hops",
+            rest: osmosis,
+            memo: `{"forward":{"receiver":"x","channel":"channel-9","next":{"forward":{"receiver":"y","channel":"channel-0"}}}}`,
+            hops: []string{"unknown-1 (channel-9)", "channel-0"},
+            receiver: "y",
+        },
+        {
+            name: "unresolved channel",
+            rest: osmosis,
+            memo: `{"forward":{"receiver":"x","channel":"channel-5"}}`,
+            hops: []string{"channel-5"},
+            receiver: "x",
+        },
+        {
+            name: "nested forward",
+            memo: `{"forward":{"receiver":"pfm","channel":"channel-1","next":{"forward":{"receiver":"cosmos1abc","channel":"channel-2"}}}}`,
+            hops: []string{"channel-1", "channel-2"},
+            receiver: "cosmos1abc",
+        },
+        {
+            name: "nested forward encoded as a string",
+            memo: `{"forward":{"receiver":"pfm","channel":"channel-1","next":"{\"forward\":{\"receiver\":\"cosmos1abc\",\"channel\":\"channel-2\"}}"}}`,
+            hops: []string{"channel-1", "channel-2"},
+            receiver: "cosmos1abc",
+        },
+        {
+            name: "memo encoded as a string",
+            memo: `"{\"forward\":{\"receiver\":\"cosmos1abc\",\"channel\":\"channel-1\"}}"`,
+            hops: []string{"channel-1"},
+            receiver: "cosmos1abc",
+        },
+        {
+            name: "osmosis crosschain swap",
+            memo: `{"wasm":{"contract":"osmo1swap","msg":{"osmosis_swap":{"output_denom":"uatom","receiver":"ibc:channel-0/cosmos1abc"}}}}`,
+            receiver: "cosmos1abc",
+            swap: "Swap to ATOM",
+            contract: "osmo1swap",
+        },
+        {
+            name: "swap and transfer",
+            memo: `{"wasm":{"contract":"osmo1skip","msg":{"swap_and_action":{"user_swap":{"swap_exact_asset_in":{"swap_venue_name":"osmosis-poolmanager"}},"min_asset":{"native":{"denom":"uosmo","amount":"1"}},"post_swap_action":{"transfer":{"to_address":"osmo1abc"}}}}}}`,
+            receiver: "osmo1abc",
+            swap: "Swap to OSMO on osmosis-poolmanager",
+            contract: "osmo1skip",
+        },
+        {
+            name: "swap and ibc transfer",
+            memo: `{"wasm":{"contract":"osmo1skip","msg":{"swap_and_action":{"min_asset":{"native":{"denom":"uatom","amount":"1"}},"post_swap_action":{"ibc_transfer":{"ibc_info":{"source_channel":"channel-0","receiver":"cosmos1abc"}}}}}}}`,
+            hops: []string{"channel-0"},
+            receiver: "cosmos1abc",
+            swap: "Swap to ATOM",
+            contract: "osmo1skip",
+        },
+        {
+            name: "unknown contract call",
+            memo: `{"wasm":{"contract":"osmo1contract","msg":{"withdraw":{},"claim":{}}}}`,
+            swap: "Execute claim, withdraw",
+            contract: "osmo1contract",
+        },
+        {
+            name: "forward then swap",
+            memo: `{"forward":{"receiver":"osmo1contract","channel":"channel-1","next":{"wasm":{"contract":"osmo1contract","msg":{"osmosis_swap":{"output_denom":"nund","receiver":"und1abc"}}}}}}`,
+            hops: []string{"channel-1"},
+            receiver: "und1abc",
+            swap: "Swap to FUND",
+            contract: "osmo1contract",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            route := decodeMemo(tt.rest, tt.memo)
+            var hops []string
+            for _, hop := range route.Hops {
+                hops = append(hops, plainString(hop))
+            }
+            if len(hops) != len(tt.hops) {
+                t.Fatalf("hops = %q, want %q", hops, tt.hops)
+            }
+            for i := range hops {
+                if hops[i] != tt.hops[i] {
+                    t.Errorf("hop %d = %q, want %q", i, hops[i], tt.hops[i])
+                }
+            }
+            if route.Receiver != tt.receiver {
+                t.Errorf("receiver = %q, want %q", route.Receiver, tt.receiver)
+            }
+            if route.Swap != tt.swap {
+                t.Errorf("swap = %q, want %q", route.Swap, tt.swap)
+            }
+            if route.Contract != tt.contract {
+                t.Errorf("contract = %q, want %q", route.Contract, tt.contract)
+            }
+        })
+    }
+}
@@ -0,0 +1,739 @@
+package main
+
+import (
+    "encoding/json"
+    "log"
+
+    "github.com/fatih/color"
+)
+
+// The built-in message types, each handler fills the message for its message action
+// Returns false if the message should not be sent
+
+// On chain transfers
+func handleTransfer(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.TransferSender) < 1 ||
+    len(events.TransferRecipient) < 2 ||
+    len(events.TransferAmount) < 2 {
+        return false
+    }
+    msg.Data = MessageData{
+        "sender": events.TransferSender[0],
+        "recipient": events.TransferRecipient[1],
+        "amount": events.TransferAmount[1],
+    }
+    msg.Doc.Title = "📬 Transfer 📬"
+    msg.Doc.Add("Sender", accountText(events.TransferSender[0]))
+    msg.Doc.Add("Recipient", accountText(events.TransferRecipient[1]))
+    msg.Doc.Add("Amount", amountText(events.TxHash[0], events.TransferAmount[1]))
+    msg.Amount = events.TransferAmount[1]
+    return isAllowedAmount(*msg)
+}
+
+// One sender to many recipients, the first transfer is the fee
+func handleMultiSend(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.MessageSender) < 1 ||
+    len(events.TransferRecipient) < 2 ||
+    len(events.TransferAmount) != len(events.TransferRecipient) {
+        return false
+    }
+    msg.Doc.Title = "📦 Multi Send 📦"
+    total := mkRecipientSummary(msg, events.MessageSender[0], events.TransferRecipient[1:], events.TransferAmount[1:], events.TxHash[0])
+    msg.Amount = total
+    return isAllowedAmount(*msg)
+}
+
+// FUND > Other Chain IBC
+func handleIBCOut(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.IBCTransferSender) < 1 ||
+    len(events.IBCTransferRecipient) < 1 ||
+    len(events.TransferAmount) < 2 {
+        return false
+    }
+    msg.Doc.Title = "⚛️ IBC Out ⚛️"
+    var route []Text
+    var hops MemoRoute
+    if len(events.SendPacketSrcChannel) > 0 {
+        var data FungibleTokenPacketData
+        if len(events.SendPacketData) > 0 {
+            json.Unmarshal([]byte(events.SendPacketData[0]), &data)
+        }
+        route, hops = mkRoute(events.SendPacketSrcChannel[0], false, data.Memo)
+        msg.Doc.Add("Route", route...)
+    }
+    msg.Data = MessageData{
+        "route": plainString(route),
+        "sender": events.IBCTransferSender[0],
+        "recipient": events.IBCTransferRecipient[0],
+        "final_recipient": hops.Receiver,
+        "swap": hops.Swap,
+        "contract": hops.Contract,
+        "amount": events.TransferAmount[1],
+    }
+    msg.Doc.Add("Sender", accountText(events.IBCTransferSender[0]))
+    msg.Doc.Add("Recipient", accountText(events.IBCTransferRecipient[0]))
+    hops.AddFields(&msg.Doc)
+    msg.Doc.Add("Amount", amountText(events.TxHash[0], events.TransferAmount[1]))
+    // Remember the packet, in case the transfer fails and is refunded
+    if config.Config.MessagesConfig.IBCFailed.Enabled &&
+    len(events.SendPacketSequence) > 0 &&
+    len(events.SendPacketSrcChannel) > 0 {
+        msg.Packet = packetKey(events.SendPacketSrcChannel[0], events.SendPacketSequence[0])
+        recordPacket(msg.Packet, OutboundPacket{
+            Sender: events.IBCTransferSender[0],
+            Recipient: events.IBCTransferRecipient[0],
+            Amount: events.TransferAmount[1],
+            TxHash: events.TxHash[0],
+        })
+    }
+    msg.Amount = events.TransferAmount[1]
+    return isAllowedAmount(*msg)
+}
+
+// IBC Out timed out, and was refunded to the sender
+func handleIBCTimeout(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.TimeoutPacketSequence) < 1 ||
+    len(events.TimeoutPacketSrcChannel) != len(events.TimeoutPacketSequence) ||
+    len(events.TimeoutRefundReceiver) != len(events.TimeoutPacketSequence) ||
+    len(events.TimeoutRefundAmount) != len(events.TimeoutPacketSequence) ||
+    len(events.TimeoutRefundDenom) != len(events.TimeoutPacketSequence) {
+        return false
+    }
+    var refunds []OutboundPacket
+    var reasons []string
+    for i, seq := range events.TimeoutPacketSequence {
+        packet, ok := takePacket(packetKey(events.TimeoutPacketSrcChannel[i], seq))
+        if !ok {
+            // Sent before the scanner started, only the refund is known
+            packet = OutboundPacket{
+                Sender: events.TimeoutRefundReceiver[i],
+                Amount: events.TimeoutRefundAmount[i] + packetDenom(events.TimeoutRefundDenom[i]),
+            }
+        }
+        refunds = append(refunds, packet)
+        reasons = append(reasons, "Timed out")
+    }
+    return mkRefundMessage(msg, refunds, reasons, events.TxHash[0])
+}
+
+// IBC Out rejected by the other chain, and refunded to the sender
+func handleIBCAcknowledgement(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.AckPacketSequence) < 1 ||
+    len(events.AckPacketSrcChannel) != len(events.AckPacketSequence) ||
+    len(events.IBCPacketAck) != len(events.AckPacketSequence) ||
+    len(events.IBCForeignSender) != len(events.AckPacketSequence) ||
+    len(events.IBCPacketReceiver) != len(events.AckPacketSequence) ||
+    len(events.IBCPacketAmount) != len(events.AckPacketSequence) ||
+    len(events.IBCPacketDenom) != len(events.AckPacketSequence) {
+        return false
+    }
+    var refunds []OutboundPacket
+    var reasons []string
+    for i, seq := range events.AckPacketSequence {
+        // Successful transfers only need to be forgotten
+        packet, ok := takePacket(packetKey(events.AckPacketSrcChannel[i], seq))
+        if !isErrorAck(events.IBCPacketAck[i]) {
+            continue
+        }
+        if !ok {
+            // Sent before the scanner started, only the acknowledgement is known
+            packet = OutboundPacket{
+                Sender: events.IBCForeignSender[i],
+                Recipient: events.IBCPacketReceiver[i],
+                Amount: events.IBCPacketAmount[i] + packetDenom(events.IBCPacketDenom[i]),
+            }
+        }
+        refunds = append(refunds, packet)
+        reasons = append(reasons, ackReason(events.IBCPacketAck[i]))
+    }
+    if len(refunds) < 1 || !mkRefundMessage(msg, refunds, reasons, events.TxHash[0]) {
+        return false
+    }
+    return true
+}
+
+// Withdraw rewards, from one or many validators
+func handleRewards(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.WithdrawRewardsDelegator) < 1 ||
+    len (events.WithdrawRewardsValidator) < 1 ||
+    len (events.WithdrawRewardsAmount) < 1 {
+        return false 
+    }
+    msg.Doc.Title = "💵 Withdraw Reward 💵"
+    msg.Doc.Add("Delegator", accountText(events.WithdrawRewardsDelegator[0]))
+    msg.Doc.Validator = events.WithdrawRewardsValidator[0]
+    var total string
+    var lines [][]Text
+    var validators []map[string]string
+    totaler := denomTotaler()
+    for i, val := range events.WithdrawRewardsValidator{
+        lines = append(lines, []Text{accountText(val)}, []Text{coinsText(events.WithdrawRewardsAmount[i])})
+        total = totaler(events.WithdrawRewardsAmount[i])
+        validators = append(validators, map[string]string{"validator": val, "amount": events.WithdrawRewardsAmount[i]})
+    }
+    msg.Doc.AddList("Validators", lines)
+    msg.Doc.AddGroup("Total", amountText(events.TxHash[0], total))
+    msg.Data = MessageData{
+        "delegator": events.WithdrawRewardsDelegator[0],
+        "validators": validators,
+        "total": total,
+    }
+    msg.Amount = total
+    return isAllowedAmount(*msg)
+}
+
+// Withdraw commission
+func handleCommission(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.WithdrawCommissionAmount) < 1 ||
+    len(events.WithdrawRewardsDelegator) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "validator": events.WithdrawRewardsDelegator[0],
+        "amount": events.WithdrawCommissionAmount[0],
+    }
+    msg.Doc.Title = "💸 Withdraw Commission 💸"
+    msg.Doc.Add("Validator", accountText(events.WithdrawRewardsDelegator[0]))
+    msg.Doc.Validator = events.WithdrawRewardsDelegator[0]
+    msg.Doc.Add("Amount", amountText(events.TxHash[0], events.WithdrawCommissionAmount[0]))
+    msg.Amount = events.WithdrawCommissionAmount[0]
+    return isAllowedAmount(*msg)
+}
+
+// Delegations
+func handleDelegations(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.DelegateValidator) < 1 ||
+    len(events.MessageSender) < 1 ||
+    len(events.DelegateAmount) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "validator": events.DelegateValidator[0],
+        "delegator": events.MessageSender[0],
+        "amount": events.DelegateAmount[0],
+    }
+    msg.Doc.Title = "❤️ Delegate ❤️"
+    msg.Doc.Add("Validator", accountText(events.DelegateValidator[0]))
+    msg.Doc.Validator = events.DelegateValidator[0]
+    msg.Doc.Add("Delegator", accountText(events.MessageSender[0]))
+    msg.Doc.Add("Amount", amountText(events.TxHash[0], events.DelegateAmount[0]))
+    msg.Amount = events.DelegateAmount[0]
+    return isAllowedAmount(*msg)
+}
+
+// Undelegations
+func handleUndelegations(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.UnbondAmount) < 1 ||
+    len(events.MessageSender) < 1 ||
+    len(events.UnbondValidator) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "validator": events.UnbondValidator[0],
+        "delegator": events.MessageSender[0],
+        "amount": events.UnbondAmount[0],
+    }
+    msg.Doc.Title = "💀 Undelegate 💀"
+    msg.Doc.Add("Validator", accountText(events.UnbondValidator[0]))
+    msg.Doc.Validator = events.UnbondValidator[0]
+    msg.Doc.Add("Delegator", accountText(events.MessageSender[0]))
+    msg.Doc.Add("Amount", amountText(events.TxHash[0], events.UnbondAmount[0]))
+    msg.Amount = events.UnbondAmount[0]
+    return isAllowedAmount(*msg)
+}
+
+// Redelegations
+func handleRedelegations(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.RedelegateSourceValidator) < 1 ||
+    len(events.RedelegateDestinationValidator) < 1 ||
+    len(events.RedelegateAmount) < 1 ||
+    len(events.MessageSender) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "source": events.RedelegateSourceValidator[0],
+        "destination": events.RedelegateDestinationValidator[0],
+        "delegator": events.MessageSender[0],
+        "amount": events.RedelegateAmount[0],
+    }
+    msg.Doc.Title = "💞 Redelegate 💞"
+    msg.Doc.Add("Validators", accountText(events.RedelegateSourceValidator[0]), plain(" → "),
+        accountText(events.RedelegateDestinationValidator[0]))
+    msg.Doc.Add("Delegator", accountText(events.MessageSender[0]))
+    msg.Doc.Add("Amount", amountText(events.TxHash[0], events.RedelegateAmount[0]))
+    msg.Doc.Validator = events.RedelegateDestinationValidator[0]
+    msg.Amount = events.RedelegateAmount[0]
+    return isAllowedAmount(*msg)
+}
+
+// REStake transactions, rewards compounded by the validators' bot through authz
+func handleRestake(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.WithdrawRewardsValidator) < 1 ||
+    len(events.MessageSender) < 1 ||
+    len(events.TransferAmount) < 1 {
+        return false 
+    }
+    msg.Doc.Title = "♻️ REStake ♻️"
+    msg.Doc.Add("Validator", accountText(events.WithdrawRewardsValidator[0]))
+    msg.Doc.Validator = events.WithdrawRewardsValidator[0]
+    j := 0
+    var lines [][]Text
+    var total string
+    var delegators []map[string]string
+    totaler := denomTotaler()
+    for i, delegator := range events.MessageSender {
+        if i >= 2 {
+            if i % 2 == 0 {
+                j += 1
+                lines = append(lines, []Text{accountText(delegator)}, []Text{coinsText(events.TransferAmount[j])})
+                total = totaler(events.TransferAmount[j])
+                delegators = append(delegators, map[string]string{"delegator": delegator, "amount": events.TransferAmount[j]})
+            }
+        }
+    }
+    msg.Doc.AddList("Delegators", lines)
+    msg.Doc.AddGroup("Total REStaked", amountText(events.TxHash[0], total))
+    msg.Data = MessageData{
+        "validator": events.WithdrawRewardsValidator[0],
+        "delegators": delegators,
+        "total": total,
+    }
+    msg.Amount = total
+    return isAllowedAmount(*msg)
+}
+
+// Other Chain > FUND IBC
+func handleIBCIn(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.IBCForeignSender) < 1 ||
+    len(events.TransferAmount) < 2 ||
+    len(events.TransferRecipient) < 2 {
+        return false
+    }
+    msg.Doc.Title = "⚛️ IBC In ⚛️"
+    var route []Text
+    var hops MemoRoute
+    if len(events.RecvPacketDstChannel) > 0 {
+        var memo string
+        if len(events.IBCPacketMemo) > 0 {
+            memo = events.IBCPacketMemo[0]
+        }
+        route, hops = mkRoute(events.RecvPacketDstChannel[0], true, memo)
+        msg.Doc.Add("Route", route...)
+    }
+    msg.Data = MessageData{
+        "route": plainString(route),
+        "sender": events.IBCForeignSender[0],
+        "recipient": events.TransferRecipient[1],
+        "final_recipient": hops.Receiver,
+        "swap": hops.Swap,
+        "contract": hops.Contract,
+        "amount": events.TransferAmount[1],
+    }
+    msg.Doc.Add("Sender", accountText(events.IBCForeignSender[0]))
+    msg.Doc.Add("Recipient", accountText(events.TransferRecipient[1]))
+    hops.AddFields(&msg.Doc)
+    msg.Doc.Add("Amount", amountText(events.TxHash[0], events.TransferAmount[1]))
+    msg.Amount = events.TransferAmount[1]
+    return isAllowedAmount(*msg)
+}
+
+// Register new Starname -> Account
+func handleRegisterAccount(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.AccountName) < 1 ||
+    len(events.DomainName) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": events.AccountName[0] + "*" + events.DomainName[0],
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Register Starname ⭐"
+    msg.Doc.Subtitle = events.AccountName[0] + "*" + events.DomainName[0]
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Register new Starname -> Domain
+func handleRegisterDomain(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.DomainName) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": "*" + events.DomainName[0],
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Register Starname ⭐"
+    msg.Doc.Subtitle = "*" + events.DomainName[0]
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Transfer Starname -> Account
+func handleTransferAccount(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.AccountName) < 1 ||
+    len(events.DomainName) < 1 ||
+    len(events.MessageSender) < 1 ||
+    len(events.NewAccountOwner) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": events.AccountName[0] + "*" + events.DomainName[0],
+        "sender": events.MessageSender[0],
+        "recipient": events.NewAccountOwner[0],
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Transfer Starname ⭐"
+    msg.Doc.Subtitle = events.AccountName[0] + "*" + events.DomainName[0]
+    msg.Doc.AddGroup("Sender", accountText(events.MessageSender[0]))
+    msg.Doc.AddGroup("Recipient", accountText(events.NewAccountOwner[0]))
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Transfer Starname -> Domain
+func handleTransferDomain(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.DomainName) < 1 ||
+    len(events.MessageSender) < 1 ||
+    len(events.NewDomainOwner) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": "*" + events.DomainName[0],
+        "sender": events.MessageSender[0],
+        "recipient": events.NewDomainOwner[0],
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Transfer Starname ⭐"
+    msg.Doc.Subtitle = "*" + events.DomainName[0]
+    msg.Doc.AddGroup("Sender", accountText(events.MessageSender[0]))
+    msg.Doc.AddGroup("Recipient", accountText(events.NewDomainOwner[0]))
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Delete Starname -> Account
+func handleDeleteAccount(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.AccountName) < 1 ||
+    len(events.DomainName) < 1 {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": events.AccountName[0] + "*" + events.DomainName[0],
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Delete Starname ⭐"
+    msg.Doc.Subtitle = events.AccountName[0] + "*" + events.DomainName[0]
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Renew Starname -> Account
+func handleRenewAccount(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.AccountName) < 1 ||
+    len(events.DomainName) < 1 {
+        return false
+    }
+    expires := getStarnameExpiry(events.AccountName[0], events.DomainName[0])
+    msg.Data = MessageData{
+        "starname": events.AccountName[0] + "*" + events.DomainName[0],
+        "expires": expires,
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Renew Starname ⭐"
+    msg.Doc.Subtitle = events.AccountName[0] + "*" + events.DomainName[0]
+    msg.Doc.Add("Expires", plain(expires))
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Renew Starname -> Domain
+func handleRenewDomain(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.DomainName) < 1 {
+        return false
+    }
+    expires := getStarnameExpiry("", events.DomainName[0])
+    msg.Data = MessageData{
+        "starname": "*" + events.DomainName[0],
+        "expires": expires,
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Renew Starname ⭐"
+    msg.Doc.Subtitle = "*" + events.DomainName[0]
+    msg.Doc.Add("Expires", plain(expires))
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Linked addresses of a Starname changed
+func handleReplaceResources(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.TxHeight) < 1 {
+        return false
+    }
+    var replace StarnameReplaceMsg
+    if err := getTxMessage(events.TxHash[0], tx.Action, &replace); err != nil {
+        log.Println(color.YellowString("Failed to get Starname resources: %v", err))
+        return false
+    }
+    // Without the previous resources the changes are unknown, so only the current resources are listed
+    old, err := getPreviousResources(replace.Name, replace.Domain, events.TxHeight[0])
+    var added, removed []StarnameResource
+    if err != nil {
+        log.Println(color.YellowString("Failed to get previous Starname resources: %v", err))
+    } else {
+        added, removed = diffResources(old, replace.NewResources)
+    }
+    msg.Data = MessageData{
+        "starname": replace.Name + "*" + replace.Domain,
+        "owner": replace.Owner,
+        "added": mkResources(added),
+        "removed": mkResources(removed),
+        "resources": mkResources(replace.NewResources),
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Starname Addresses Updated ⭐"
+    msg.Doc.Subtitle = replace.Name + "*" + replace.Domain
+    msg.Doc.Add("Owner", accountText(replace.Owner))
+    if err != nil {
+        msg.Doc.Add("Changes", plain("Unknown, the previous addresses could not be fetched"))
+        if len(replace.NewResources) > 0 {
+            msg.Doc.AddList("Addresses", mkResourceLines(replace.NewResources))
+        }
+    }
+    if len(added) > 0 {
+        msg.Doc.AddList("Added", mkResourceLines(added))
+    }
+    if len(removed) > 0 {
+        msg.Doc.AddList("Removed", mkResourceLines(removed))
+    }
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Metadata URI of a Starname changed
+func handleReplaceMetadata(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    var replace StarnameReplaceMsg
+    if err := getTxMessage(events.TxHash[0], tx.Action, &replace); err != nil {
+        log.Println(color.YellowString("Failed to get Starname metadata: %v", err))
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": replace.Name + "*" + replace.Domain,
+        "owner": replace.Owner,
+        "metadata": replace.NewMetadataURI,
+        "fee": starnameFee(events.TransferAmount),
+    }
+    msg.Doc.Title = "⭐️️ Starname Metadata Updated ⭐"
+    msg.Doc.Subtitle = replace.Name + "*" + replace.Domain
+    msg.Doc.Add("Owner", accountText(replace.Owner))
+    msg.Doc.Add("Metadata", plain(replace.NewMetadataURI))
+    addStarnameFee(&msg.Doc, events.TxHash[0], events.TransferAmount)
+    return true
+}
+
+// Starname put up for sale
+func handleCreateEscrow(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.CreateEscrowID) < 1 ||
+    len(events.CreateEscrowSeller) < 1 ||
+    len(events.CreateEscrowPrice) < 1 ||
+    len(events.CreateEscrowObject) < 1 {
+        return false
+    }
+    var deadline string
+    if len(events.CreateEscrowDeadline) > 0 {
+        deadline = events.CreateEscrowDeadline[0]
+    }
+    escrow := storeEscrow(Escrow{ID: unquoteEvent(events.CreateEscrowID[0])},
+        events.CreateEscrowSeller[0], events.CreateEscrowPrice[0], events.CreateEscrowObject[0], deadline)
+    price := coinsToAmount(escrow.Price)
+    if escrow.Object.Name == "" || price == "" {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": mkStarname(escrow.Object),
+        "seller": escrow.Seller,
+        "price": price,
+        "expires": formatExpiry(escrow.Object.ValidUntil),
+    }
+    msg.Doc.Title = "🏷️ Starname For Sale 🏷️"
+    msg.Doc.Subtitle = mkStarname(escrow.Object)
+    msg.Doc.Add("Seller", accountText(escrow.Seller))
+    msg.Doc.Add("Price", amountText(events.TxHash[0], price))
+    msg.Doc.Add("Expires", plain(formatExpiry(escrow.Object.ValidUntil)))
+    msg.Amount = price
+    return isAllowedAmount(*msg)
+}
+
+// Starname sale price or seller updated
+func handleUpdateEscrow(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.UpdateEscrowID) < 1 ||
+    len(events.TxHeight) < 1 {
+        return false
+    }
+    escrow, ok := getEscrow(events.UpdateEscrowID[0], events.TxHeight[0])
+    if !ok {
+        return false
+    }
+    var seller, price, deadline string
+    if len(events.UpdateEscrowSeller) > 0 {
+        seller = events.UpdateEscrowSeller[0]
+    }
+    if len(events.UpdateEscrowPrice) > 0 {
+        price = events.UpdateEscrowPrice[0]
+    }
+    if len(events.UpdateEscrowDeadline) > 0 {
+        deadline = events.UpdateEscrowDeadline[0]
+    }
+    escrow = storeEscrow(escrow, seller, price, "", deadline)
+    price = coinsToAmount(escrow.Price)
+    if escrow.Object.Name == "" || price == "" {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": mkStarname(escrow.Object),
+        "seller": escrow.Seller,
+        "price": price,
+        "expires": formatExpiry(escrow.Object.ValidUntil),
+    }
+    msg.Doc.Title = "🏷️ Starname Sale Updated 🏷️"
+    msg.Doc.Subtitle = mkStarname(escrow.Object)
+    msg.Doc.Add("Seller", accountText(escrow.Seller))
+    msg.Doc.Add("Price", amountText(events.TxHash[0], price))
+    msg.Doc.Add("Expires", plain(formatExpiry(escrow.Object.ValidUntil)))
+    msg.Amount = price
+    return isAllowedAmount(*msg)
+}
+
+// Starname bought from an escrow
+func handleTransferToEscrow(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.CompleteEscrowID) < 1 ||
+    len(events.CompleteEscrowBuyer) < 1 ||
+    len(events.TxHeight) < 1 {
+        return false
+    }
+    escrow, ok := getEscrow(events.CompleteEscrowID[0], events.TxHeight[0])
+    forgetEscrow(events.CompleteEscrowID[0])
+    if !ok {
+        return false
+    }
+    price := coinsToAmount(escrow.Price)
+    if price == "" {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": mkStarname(escrow.Object),
+        "seller": escrow.Seller,
+        "buyer": unquoteEvent(events.CompleteEscrowBuyer[0]),
+        "price": price,
+        "expires": formatExpiry(escrow.Object.ValidUntil),
+    }
+    msg.Doc.Title = "🤝 Starname Sold 🤝"
+    msg.Doc.Subtitle = mkStarname(escrow.Object)
+    msg.Doc.Add("Seller", accountText(escrow.Seller))
+    msg.Doc.Add("Buyer", accountText(unquoteEvent(events.CompleteEscrowBuyer[0])))
+    msg.Doc.Add("Price", amountText(events.TxHash[0], price))
+    msg.Doc.Add("Expires", plain(formatExpiry(escrow.Object.ValidUntil)))
+    msg.Amount = price
+    return isAllowedAmount(*msg)
+}
+
+// Starname sale cancelled, or expired and refunded to the seller
+func handleRefundEscrow(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.RefundEscrowID) < 1 ||
+    len(events.TxHeight) < 1 {
+        return false
+    }
+    escrow, ok := getEscrow(events.RefundEscrowID[0], events.TxHeight[0])
+    forgetEscrow(events.RefundEscrowID[0])
+    if !ok {
+        return false
+    }
+    price := coinsToAmount(escrow.Price)
+    if price == "" {
+        return false
+    }
+    msg.Data = MessageData{
+        "starname": mkStarname(escrow.Object),
+        "seller": escrow.Seller,
+        "price": price,
+        "expires": formatExpiry(escrow.Object.ValidUntil),
+    }
+    msg.Doc.Title = "↩️ Starname Sale Refunded ↩️"
+    msg.Doc.Subtitle = mkStarname(escrow.Object)
+    msg.Doc.Add("Seller", accountText(escrow.Seller))
+    msg.Doc.Add("Price", amountText(events.TxHash[0], price))
+    msg.Doc.Add("Expires", plain(formatExpiry(escrow.Object.ValidUntil)))
+    msg.Amount = price
+    return isAllowedAmount(*msg)
+}
+
+// Unrecognized actions, E.G. from modules added by a chain upgrade
+func handleOther(tx TxContext, msg *MessageResponse) bool {
+    events := tx.Events
+    if len(events.MessageSender) < 1 || !isAllowedAction(config.Config.MessagesConfig.Other, tx.Action) {
+        return false
+    }
+    msg.Doc.Title = "🔹 " + actionName(tx.Action) + " 🔹"
+    msg.Doc.Add("Module", plain(actionModule(tx.Action)))
+    msg.Doc.Add("Action", plain(tx.Action))
+    msg.Doc.Add("Signer", accountText(events.MessageSender[0]))
+    msg.Data = MessageData{
+        "name": actionName(tx.Action),
+        "module": actionModule(tx.Action),
+        "signer": events.MessageSender[0],
+        "amounts": []string{},
+        "total": "",
+    }
+    // The first transfer is the fee
+    if len(events.TransferAmount) < 2 {
+        msg.Doc.Add("TX", hashText(events.TxHash[0]))
+    } else {
+        var total string
+        totaler := denomTotaler()
+        var lines [][]Text
+        for _, amount := range events.TransferAmount[1:] {
+            lines = append(lines, []Text{amountText(events.TxHash[0], amount)})
+            total = totaler(amount)
+        }
+        msg.Doc.AddList("Amounts", lines)
+        msg.Data["amounts"] = events.TransferAmount[1:]
+        msg.Data["total"] = total
+        msg.Amount = total
+        if !isAllowedAmount(*msg) {
+            return false
+        }
+    }
+    return true
+}
+
+// Returns the product fee of a Starname message, the first transfer of the TX is the gas fee
+func starnameFee(amounts []string) string {
+    if len(amounts) < 2 {
+        return ""
+    }
+    return amounts[1]
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Time between price refreshes
+const priceInterval = 5 * time.Minute
+
+// CoinGecko IDs per /simple/price request, to keep the URL short
+const priceBatchSize = 100
+
+// Backoff after CoinGecko rate limits the requests, doubled every time up to the maximum
+const (
+    minPriceBackoff = time.Minute
+    maxPriceBackoff = 30 * time.Minute
+)
+
+// A price and when it was fetched
+type fetchedPrice struct {
+    Price     float64
+    FetchedAt time.Time
+}
+
+// Last good prices and 24h volumes keyed by CoinGecko ID and currency code, and the IDs to refresh
+// Prices are only queried once something needs them, in every configured currency
+var prices = struct {
+    sync.Mutex
+    m       map[string]map[string]fetchedPrice
+    volumes map[string]map[string]float64
+    wanted  map[string]bool
+    backoff time.Duration
+    until   time.Time
+}{m: map[string]map[string]fetchedPrice{}, volumes: map[string]map[string]float64{}, wanted: map[string]bool{}}
+
+// Wakes the price service when a new price is wanted
+var priceWanted = make(chan struct{}, 1)
+
+// Returns the currencies CoinGecko quotes prices in, E.G. usd, eur and btc
+func supportedCurrencies() ([]string, error) {
+    var currencies []string
+    err := getData("https://api.coingecko.com/api/v3/simple/supported_vs_currencies", &currencies)
+    return currencies, err
+}
+
+// Returns the currency code to show amounts in, the first configured currency if none is given
+func currencyOrDefault(currency string) string {
+    if currency == "" {
+        return config.Currencies[0]
+    }
+    return currency
+}
+
+// Returns the name of the currency as shown in the messages, E.G. usd becomes USD
+func currencyName(currency string) string {
+    return strings.ToUpper(currencyOrDefault(currency))
+}
+
+// Returns the last good price of the CoinGecko ID in the currency and when it was fetched, never blocks
+// Returns 0 if the price isn't known yet, the ID is then queried with the next refresh
+func getPrice(id string, currency string) (float64, time.Time) {
+    if id == "" {
+        return 0, time.Time{}
+    }
+    prices.Lock()
+    defer prices.Unlock()
+    if !prices.wanted[id] {
+        prices.wanted[id] = true
+        select {
+        case priceWanted <- struct{}{}:
+        default:
+        }
+    }
+    price := prices.m[id][currencyOrDefault(currency)]
+    return price.Price, price.FetchedAt
+}
+
+// Returns the last good 24h trading volume of the CoinGecko ID in the currency, 0 if it isn't known
+// Only the volumes of IDs with a wanted price are queried
+func getVolume(id string, currency string) float64 {
+    prices.Lock()
+    defer prices.Unlock()
+    return prices.volumes[id][currencyOrDefault(currency)]
+}
+
+// Refreshes the wanted prices of every provider every price interval, and shortly after a new price is wanted
+func priceService() {
+    ticker := time.NewTicker(priceInterval)
+    for {
+        refreshPrices()
+        for _, provider := range priceProviders {
+            if p, ok := provider.(refreshingProvider); ok {
+                p.refresh()
+            }
+        }
+        select {
+        case <-ticker.C:
+        case <-priceWanted:
+            // Wait for the other IDs of the message, so they share a request
+            time.Sleep(5 * time.Second)
+        }
+    }
+}
+
+// Queries the wanted prices in batches, unless CoinGecko asked to back off
+func refreshPrices() {
+    prices.Lock()
+    if time.Now().Before(prices.until) {
+        prices.Unlock()
+        return
+    }
+    var ids []string
+    for id := range prices.wanted {
+        ids = append(ids, id)
+    }
+    prices.Unlock()
+    sort.Strings(ids)
+    for i := 0; i < len(ids); i += priceBatchSize {
+        batch := ids[i:min(i + priceBatchSize, len(ids))]
+        quotes, err := getSimplePrices(batch)
+        var limited *rateLimitError
+        if errors.As(err, &limited) {
+            prices.Lock()
+            prices.backoff = min(max(prices.backoff * 2, minPriceBackoff, limited.retryAfter), maxPriceBackoff)
+            prices.until = time.Now().Add(prices.backoff)
+            prices.Unlock()
+            log.Println(color.YellowString("CoinGecko rate limited the price requests, backing off for " + prices.backoff.String()))
+            return
+        }
+        if err != nil {
+            log.Println(color.YellowString("Failed to get prices: %v", err))
+            continue
+        }
+        prices.Lock()
+        prices.backoff = 0
+        for _, id := range batch {
+            if prices.m[id] == nil {
+                prices.m[id] = map[string]fetchedPrice{}
+                prices.volumes[id] = map[string]float64{}
+            }
+            // Rate limited responses can contain zeros instead of an error, the last good price is kept
+            // The volumes are in the same object as the prices, E.G. usd_24h_vol
+            for key, value := range quotes[id] {
+                if value <= 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+                    continue
+                }
+                if currency, ok := strings.CutSuffix(key, "_24h_vol"); ok {
+                    prices.volumes[id][currency] = value
+                } else {
+                    prices.m[id][key] = fetchedPrice{Price: value, FetchedAt: time.Now()}
+                }
+            }
+        }
+        prices.Unlock()
+    }
+}
+
+// Returned when CoinGecko rate limits the requests, with the time it asked to wait
+type rateLimitError struct {
+    retryAfter time.Duration
+}
+
+func (err *rateLimitError) Error() string {
+    return "rate limited, retry after " + err.retryAfter.String()
+}
+
+// Returns the prices and 24h volumes of the CoinGecko IDs keyed by ID and currency, in the configured currencies
+func getSimplePrices(ids []string) (map[string]map[string]float64, error) {
+    url := "https://api.coingecko.com/api/v3/simple/price?include_24hr_vol=true&vs_currencies=" + strings.Join(config.Currencies, ",") +
+        "&ids=" + strings.Join(ids, ",")
+    resp, err := http.Get(url)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusTooManyRequests {
+        seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+        return nil, &rateLimitError{retryAfter: time.Duration(seconds) * time.Second}
+    }
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("unexpected status %s from: %s", resp.Status, url)
+    }
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, err
+    }
+    var quotes map[string]map[string]float64
+    if err := json.Unmarshal(body, &quotes); err != nil {
+        return nil, err
+    }
+    return quotes, nil
+}
+
+// Returns the all-time high price of the CoinGecko ID in the currency
+func getATH(id string, currency string) (float64, error) {
+    var res struct {
+        MarketData struct {
+            ATH map[string]float64 `json:"ath"`
+        } `json:"market_data"`
+    }
+    url := "https://api.coingecko.com/api/v3/coins/" + id + "?localization=false&tickers=false&community_data=false&developer_data=false"
+    if err := getData(url, &res); err != nil {
+        return 0, err
+    }
+    ath := res.MarketData.ATH[currencyOrDefault(currency)]
+    if ath <= 0 || math.IsNaN(ath) || math.IsInf(ath, 0) {
+        return 0, fmt.Errorf("no all-time high for %s", id)
+    }
+    return ath, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// An asset to price, identified by its base denom on its origin chain
+type PricedAsset struct {
+    Denom       string
+    Exponent    int
+    CoinGeckoID string
+}
+
+// A price in a currency, the source it came from and when it was fetched
+// Prices older than the max age are stale
+type Quote struct {
+    Price     float64
+    Source    string
+    FetchedAt time.Time
+    Stale     bool
+}
+
+// A source of prices, which must never block
+type PriceProvider interface {
+    // Returns the price of the asset in the currency and when it was fetched, false if the source doesn't know it (yet)
+    Price(asset PricedAsset, currency string) (float64, time.Time, bool)
+}
+
+// Providers which query their prices periodically, refreshed along with the CoinGecko prices
+type refreshingProvider interface {
+    refresh()
+}
+
+// The price providers by the name used in the config
+var priceProviders = map[string]PriceProvider{
+    "coingecko": coinGeckoProvider{},
+    "osmosis":   &osmosisProvider{prices: map[string]fetchedPrice{}, wanted: map[string]bool{}},
+    "static":    staticProvider{},
+    "file":      &fileProvider{},
+}
+
+// Returns the price of the asset in the currency from the first of its sources with a fresh price
+// Without a fresh price the first stale price is used, unless stale prices are hidden
+// The price is 0 if none of the sources know it
+func assetQuote(asset PricedAsset, currency string) Quote {
+    currency = currencyOrDefault(currency)
+    var stale Quote
+    for _, source := range priceSources(asset.Denom) {
+        price, fetchedAt, ok := priceProviders[source].Price(asset, currency)
+        if !ok || price <= 0 || math.IsInf(price, 0) {
+            continue
+        }
+        quote := Quote{Price: price, Source: source, FetchedAt: fetchedAt, Stale: isStale(fetchedAt)}
+        if !quote.Stale {
+            return quote
+        }
+        if stale.Price == 0 {
+            stale = quote
+        }
+    }
+    if config.Config.PricesConfig.Stale == "hide" {
+        return Quote{}
+    }
+    return stale
+}
+
+// Checks if a price fetched at the time is older than the max age
+func isStale(fetchedAt time.Time) bool {
+    maxAge := config.Config.PricesConfig.MaxAge
+    return maxAge > 0 && time.Since(fetchedAt) > time.Duration(maxAge) * time.Minute
+}
+
+// Returns the price sources of the denom in order, the assets' own sources or the default ones
+func priceSources(denom string) []string {
+    if cfg, ok := assetPriceConfig(denom); ok && len(cfg.Sources) > 0 {
+        return cfg.Sources
+    }
+    return config.Config.PricesConfig.Sources
+}
+
+// Returns the price config of the denom, if it has one
+func assetPriceConfig(denom string) (AssetPriceConfig, bool) {
+    for _, cfg := range config.Config.PricesConfig.Assets {
+        if cfg.Denom == denom {
+            return cfg, true
+        }
+    }
+    return AssetPriceConfig{}, false
+}
+
+// Prices from CoinGecko by the assets' CoinGecko ID
+type coinGeckoProvider struct{}
+
+func (coinGeckoProvider) Price(asset PricedAsset, currency string) (float64, time.Time, bool) {
+    price, fetchedAt := getPrice(asset.CoinGeckoID, currency)
+    return price, fetchedAt, price > 0
+}
+
+// Prices set in the config, per currency. They are never stale
+type staticProvider struct{}
+
+func (staticProvider) Price(asset PricedAsset, currency string) (float64, time.Time, bool) {
+    cfg, _ := assetPriceConfig(asset.Denom)
+    price, ok := cfg.Static[currency]
+    return price, time.Now(), ok
+}
+
+// Prices from a local JSON file keyed by denom and currency, E.G. { "nund": { "usd": 0.0123 } }
+// The file is read again when it changes, its prices are as old as the file
+type fileProvider struct {
+    sync.Mutex
+    file     string
+    modified time.Time
+    prices   map[string]map[string]float64
+}
+
+func (p *fileProvider) Price(asset PricedAsset, currency string) (float64, time.Time, bool) {
+    p.Lock()
+    defer p.Unlock()
+    if p.file == "" {
+        return 0, time.Time{}, false
+    }
+    if info, err := os.Stat(p.file); err != nil {
+        log.Println(color.YellowString("Failed to read the price file: %v", err))
+    } else if !info.ModTime().Equal(p.modified) {
+        p.modified = info.ModTime()
+        data, err := os.ReadFile(p.file)
+        if err == nil {
+            var prices map[string]map[string]float64
+            if err = json.Unmarshal(data, &prices); err == nil {
+                p.prices = prices
+            }
+        }
+        if err != nil {
+            log.Println(color.YellowString("Failed to read the price file: %v", err))
+        }
+    }
+    price, ok := p.prices[asset.Denom][currency]
+    return price, p.modified, ok
+}
+
+// Prices from the spot price or TWAP of an Osmosis pool, quoted in an asset with a known price like USDC
+// The prices are queried in the background, an asset is priced after its first refresh
+// A price is as old as the older of the pool price and the price of the quote asset
+type osmosisProvider struct {
+    sync.Mutex
+    prices map[string]fetchedPrice
+    wanted map[string]bool
+}
+
+func (p *osmosisProvider) Price(asset PricedAsset, currency string) (float64, time.Time, bool) {
+    cfg, ok := assetPriceConfig(asset.Denom)
+    if !ok || cfg.OsmosisPool == 0 {
+        return 0, time.Time{}, false
+    }
+    p.Lock()
+    defer p.Unlock()
+    if !p.wanted[asset.Denom] {
+        p.wanted[asset.Denom] = true
+        select {
+        case priceWanted <- struct{}{}:
+        default:
+        }
+    }
+    quote, quoteFetchedAt := getPrice(cfg.QuoteCoinGeckoID, currency)
+    price, ok := p.prices[asset.Denom]
+    if !ok || quote == 0 {
+        return 0, time.Time{}, false
+    }
+    fetchedAt := price.FetchedAt
+    if quoteFetchedAt.Before(fetchedAt) {
+        fetchedAt = quoteFetchedAt
+    }
+    // The pool price is in base units of the quote asset per base unit of the asset
+    return price.Price * math.Pow10(asset.Exponent - cfg.QuoteExponent) * quote, fetchedAt, true
+}
+
+func (p *osmosisProvider) refresh() {
+    p.Lock()
+    var denoms []string
+    for denom := range p.wanted {
+        denoms = append(denoms, denom)
+    }
+    p.Unlock()
+    for _, denom := range denoms {
+        cfg, _ := assetPriceConfig(denom)
+        price, err := getPoolPrice(cfg)
+        if err != nil {
+            log.Println(color.YellowString("Failed to get the Osmosis price of %s: %v", denom, err))
+            continue
+        }
+        p.Lock()
+        p.prices[denom] = fetchedPrice{Price: price, FetchedAt: time.Now()}
+        p.Unlock()
+    }
+}
+
+// Returns the price of the asset of the pool in base units of the quote asset
+// Uses the TWAP when a window is configured, otherwise the spot price
+func getPoolPrice(cfg AssetPriceConfig) (float64, error) {
+    osmosis := config.Config.PricesConfig.Osmosis
+    var price string
+    if osmosis.TWAP > 0 {
+        var res struct {
+            Price string `json:"arithmetic_twap"`
+        }
+        start := time.Now().Add(-time.Duration(osmosis.TWAP) * time.Minute).UTC().Format(time.RFC3339)
+        err := getData(fmt.Sprintf("%sosmosis/twap/v1beta1/ArithmeticTwapToNow?pool_id=%d&base_asset=%s&quote_asset=%s&start_time=%s",
+            osmosis.Rest, cfg.OsmosisPool, url.QueryEscape(cfg.OsmosisDenom), url.QueryEscape(cfg.QuoteDenom), url.QueryEscape(start)), &res)
+        if err != nil {
+            return 0, err
+        }
+        price = res.Price
+    } else {
+        var res struct {
+            Price string `json:"spot_price"`
+        }
+        err := getData(fmt.Sprintf("%sosmosis/poolmanager/v1beta1/pools/%d/prices?base_asset_denom=%s&quote_asset_denom=%s",
+            osmosis.Rest, cfg.OsmosisPool, url.QueryEscape(cfg.OsmosisDenom), url.QueryEscape(cfg.QuoteDenom)), &res)
+        if err != nil {
+            return 0, err
+        }
+        price = res.Price
+    }
+    value, err := strconv.ParseFloat(price, 64)
+    if err != nil {
+        return 0, err
+    }
+    if value <= 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+        return 0, fmt.Errorf("invalid pool price %q", price)
+    }
+    return value, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+    "log"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/fatih/color"
+)
+
+// The TX being parsed, as seen by the handler of one of its message actions
+type TxContext struct {
+    Action string
+    Events Events
+    Raw    map[string][]string
+    Resp   chan MessageResponse
+}
+
+// Fills the message for the TXs containing its message action
+type MessageHandler struct {
+    TypeName string
+    Config   *MessageConfig
+    Handle   func(tx TxContext, msg *MessageResponse) bool
+}
+
+// Message types keyed by their message action, built-ins are registered first so custom types can replace them
+var handlers = map[string]MessageHandler{}
+
+// Refers to an event attribute, with an optional index, E.G. transfer.amount[1] or wasm.sender[-1]
+var eventRef = regexp.MustCompile(`^([^\[\]]+)(?:\[(-?[0-9]+)\])?$`)
+
+// Adds a message type for the message action, every message type has the hash, action and memo fields
+func register(action string, typeName string, cfg *MessageConfig, handle func(tx TxContext, msg *MessageResponse) bool, fields ...string) {
+    handlers[action] = MessageHandler{TypeName: typeName, Config: cfg, Handle: handle}
+    registerType(typeName, cfg, append(fields, "hash", "action", "memo")...)
+}
+
+// Registers the built-in message types, and the custom message types declared in the config
+func registerHandlers() {
+    m := &config.Config.MessagesConfig
+    register("/cosmos.bank.v1beta1.MsgSend", "Transfer", &m.Transfers, handleTransfer, "sender", "recipient", "amount")
+    register("/cosmos.bank.v1beta1.MsgMultiSend", "MultiSend", &m.MultiSend, handleMultiSend, "sender", "count", "recipients.recipient", "recipients.amount", "total")
+    register("/ibc.applications.transfer.v1.MsgTransfer", "IBCOut", &m.IBCOut, handleIBCOut, "route", "sender", "recipient", "final_recipient", "swap", "contract", "amount")
+    register("/ibc.core.channel.v1.MsgTimeout", "IBCFailed", &m.IBCFailed.MessageConfig, handleIBCTimeout, "refunds.sender", "refunds.recipient", "refunds.amount", "refunds.reason", "refunds.original")
+    register("/ibc.core.channel.v1.MsgAcknowledgement", "IBCFailed", &m.IBCFailed.MessageConfig, handleIBCAcknowledgement, "refunds.sender", "refunds.recipient", "refunds.amount", "refunds.reason", "refunds.original")
+    register("/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward", "Rewards", &m.Rewards, handleRewards, "delegator", "validators.validator", "validators.amount", "total")
+    register("/cosmos.distribution.v1beta1.MsgWithdrawValidatorCommission", "Commission", &m.Commission, handleCommission, "validator", "amount")
+    register("/cosmos.staking.v1beta1.MsgDelegate", "Delegations", &m.Delegations, handleDelegations, "validator", "delegator", "amount")
+    register("/cosmos.staking.v1beta1.MsgUndelegate", "Undelegations", &m.Undelegations, handleUndelegations, "validator", "delegator", "amount")
+    register("/cosmos.staking.v1beta1.MsgBeginRedelegate", "Redelegations", &m.Redelegations, handleRedelegations, "source", "destination", "delegator", "amount")
+    register("/cosmos.authz.v1beta1.MsgExec", "Restake", &m.Restake, handleRestake, "validator", "delegators.delegator", "delegators.amount", "total")
+    register("/ibc.core.channel.v1.MsgRecvPacket", "IBCIn", &m.IBCIn, handleIBCIn, "route", "sender", "recipient", "final_recipient", "swap", "contract", "amount")
+    register("/starnamed.x.starname.v1beta1.MsgRegisterAccount", "RegisterAccount", &m.RegisterAccount, handleRegisterAccount, "starname", "fee")
+    register("/starnamed.x.starname.v1beta1.MsgRegisterDomain", "RegisterDomain", &m.RegisterDomain, handleRegisterDomain, "starname", "fee")
+    register("/starnamed.x.starname.v1beta1.MsgTransferAccount", "TransferAccount", &m.TransferAccount, handleTransferAccount, "starname", "sender", "recipient", "fee")
+    register("/starnamed.x.starname.v1beta1.MsgTransferDomain", "TransferDomain", &m.TransferDomain, handleTransferDomain, "starname", "sender", "recipient", "fee")
+    register("/starnamed.x.starname.v1beta1.MsgDeleteAccount", "DeleteAccount", &m.DeleteAccount, handleDeleteAccount, "starname", "fee")
+    register("/starnamed.x.starname.v1beta1.MsgRenewAccount", "RenewAccount", &m.RenewAccount, handleRenewAccount, "starname", "expires", "fee")
+    register("/starnamed.x.starname.v1beta1.MsgRenewDomain", "RenewDomain", &m.RenewDomain, handleRenewDomain, "starname", "expires", "fee")
+    register("/starnamed.x.starname.v1beta1.MsgReplaceAccountResources", "ReplaceResources", &m.ReplaceResources, handleReplaceResources, "starname", "owner", "added[]", "removed[]", "resources[]", "fee")
+    register("/starnamed.x.starname.v1beta1.MsgReplaceAccountMetadata", "ReplaceMetadata", &m.ReplaceMetadata, handleReplaceMetadata, "starname", "owner", "metadata", "fee")
+    register("/starnamed.x.escrow.v1beta1.MsgCreateEscrow", "CreateEscrow", &m.CreateEscrow, handleCreateEscrow, "starname", "seller", "price", "expires")
+    register("/starnamed.x.escrow.v1beta1.MsgUpdateEscrow", "UpdateEscrow", &m.UpdateEscrow, handleUpdateEscrow, "starname", "seller", "price", "expires")
+    register("/starnamed.x.escrow.v1beta1.MsgTransferToEscrow", "TransferToEscrow", &m.TransferToEscrow, handleTransferToEscrow, "starname", "seller", "buyer", "price", "expires")
+    register("/starnamed.x.escrow.v1beta1.MsgRefundEscrow", "RefundEscrow", &m.RefundEscrow, handleRefundEscrow, "starname", "seller", "price", "expires")
+    // Message types without a message action
+    registerType("Other", &m.Other, "name", "module", "signer", "amounts[]", "total", "hash", "action", "memo")
+    registerType("Distributions", &m.Distributions, "sender", "count", "recipients.recipient", "recipients.amount", "total", "hash")
+    registerType("Burns", &m.Burns, "burner", "amount", "total", "hash")
+    registerType("Mints", &m.Mints, "minter", "amount", "total", "hash")
+    registerType("SupplyReport", &m.SupplyReport, "supply", "change", "burned", "minted", "burn_addresses", "period")
+    registerType("PriceAlerts", &m.PriceAlerts, "coin", "alert", "price", "change", "window", "volume")
+
+    for i := range m.Custom {
+        custom := &m.Custom[i]
+        if custom.Name == "" || custom.Action == "" {
+            log.Fatal(color.RedString("Custom message types need a name and an action, check your config"))
+        }
+        if _, ok := messageTypes[custom.Name]; ok {
+            log.Fatal(color.RedString("Custom message type name is already used: " + custom.Name))
+        }
+        var roles []string
+        for role, ref := range custom.Roles {
+            if strings.TrimSpace(role) == "" {
+                log.Fatal(color.RedString("Empty role name in custom message type " + custom.Name + ", check your config"))
+            }
+            if !eventRef.MatchString(ref) {
+                log.Fatal(color.RedString("Invalid event attribute for role " + role + " of custom message type " + custom.Name + ": " + ref))
+            }
+            roles = append(roles, role)
+        }
+        if _, ok := handlers[custom.Action]; ok {
+            log.Println(color.YellowString("Custom message type " + custom.Name + " replaces the built-in type for: " + custom.Action))
+        }
+        sort.Strings(roles)
+        register(custom.Action, custom.Name, &custom.MessageConfig, mkCustomHandler(custom), roles...)
+    }
+}
+
+// Returns true if any of the actions has a registered message type
+func hasKnownAction(actions []string) bool {
+    for _, action := range actions {
+        if _, ok := handlers[action]; ok {
+            return true
+        }
+    }
+    return false
+}
+
+// Returns the value of the referenced event attribute, the first value is used if no index is given
+// Negative indexes count from the end, E.G. [-1] is the last value
+func lookupEvent(raw map[string][]string, ref string) (string, bool) {
+    match := eventRef.FindStringSubmatch(ref)
+    if match == nil {
+        return "", false
+    }
+    values := raw[match[1]]
+    index := 0
+    if match[2] != "" {
+        index, _ = strconv.Atoi(match[2])
+    }
+    if index < 0 {
+        index += len(values)
+    }
+    if index < 0 || index >= len(values) {
+        return "", false
+    }
+    return values[index], true
+}
+
+// Returns the handler of a message type declared in the config, which resolves the roles to their event attributes
+func mkCustomHandler(custom *CustomMessageConfig) func(tx TxContext, msg *MessageResponse) bool {
+    return func(tx TxContext, msg *MessageResponse) bool {
+        for _, attr := range custom.Required {
+            if len(tx.Raw[attr]) < 1 {
+                return false
+            }
+        }
+        msg.Data = MessageData{}
+        for role, ref := range custom.Roles {
+            value, ok := lookupEvent(tx.Raw, ref)
+            if !ok {
+                return false
+            }
+            msg.Data[role] = value
+        }
+        mkCustomMessage(custom, msg, tx.Events.TxHash[0])
+        if amount, ok := msg.Data["amount"].(string); ok {
+            msg.Amount = amount
+            return isAllowedAmount(*msg)
+        }
+        return true
+    }
+}
+
+// Fills the document of a custom message type, listing its roles under its title
+// Addresses are linked, the amount is linked to the transaction and the proposal to its page on the explorer
+func mkCustomMessage(custom *CustomMessageConfig, msg *MessageResponse, hash string) {
+    msg.Doc.Title = custom.Title
+    if msg.Doc.Title == "" {
+        msg.Doc.Title = "🔹 " + actionName(custom.Action) + " 🔹"
+    }
+    var roles []string
+    for role := range custom.Roles {
+        roles = append(roles, role)
+    }
+    sort.Strings(roles)
+    for _, role := range roles {
+        value := msg.Data[role].(string)
+        name := strings.ToUpper(role[:1]) + role[1:]
+        switch role {
+        case "amount":
+            msg.Doc.Add(name, amountText(hash, value))
+        case "sender", "recipient", "validator", "delegator", "signer":
+            msg.Doc.Add(name, accountText(value))
+            if role == "validator" {
+                msg.Doc.Validator = value
+            }
+        case "proposal":
+            msg.Doc.Add(name, Text{Text: value, URL: proposalURL(value)})
+        default:
+            msg.Doc.Add(name, plain(value))
+        }
+    }
+    if _, ok := msg.Data["amount"]; !ok {
+        msg.Doc.Add("TX", hashText(hash))
+    }
+}
@@ -5,9 +5,7 @@ import (
 	"errors"
 	"io"
 	"log"
-	"math/rand"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -26,6 +24,8 @@ type AssetsResponse struct {
     } `json:"assets"`
 }
 type ChainResponse struct {
+    ChainName    string `json:"chain_name"`
+    ChainID      string `json:"chain_id"`
     PrettyName   string `json:"pretty_name"`
     Bech32Prefix string `json:"bech32_prefix"`
     Apis         struct {
@@ -40,96 +40,56 @@ type ChainResponse struct {
 type GitHubResponse struct {
     Chains []string `json:"mainnets"`
 }
+type FungibleTokenPacketData struct {
+    Amount   string `json:"amount"`
+    Denom    string `json:"denom"`
+    Receiver string `json:"receiver"`
+    Sender   string `json:"sender"`
+    Memo     string `json:"memo"`
+}
+type ClientStateResponse struct {
+    IdentifiedClientState struct {
+        ClientState struct {
+            ChainID string `json:"chain_id"`
+        } `json:"client_state"`
+    } `json:"identified_client_state"`
+}
 type IBCResponse struct {
-    DenomTrace struct {
-        BaseDenom string `json:"base_denom"` 
-    } `json:"denom_trace"`
+    DenomTrace DenomTrace `json:"denom_trace"`
 }
 type ICNSResponse struct {
     Data struct {
         PrimaryName string `json:"primary_name"`
     } `json:"data"`
 }
+type KeybaseResponse struct {
+    Them []struct {
+        Pictures struct {
+            Primary struct {
+                URL string `json:"url"`
+            } `json:"primary"`
+        } `json:"pictures"`
+    } `json:"them"`
+}
 type TxResponse struct {
     Tx struct {
         Body struct {
-            Memo string `json:"memo"`
+            Messages []json.RawMessage `json:"messages"`
+            Memo     string            `json:"memo"`
         } `json:"body"`
     }
 }
-type CoinGeckoResponse struct {
-    MarketData struct {
-        CurrentPrice struct {
-			Aed  float64 `json:"aed"`
-			Ars  float64 `json:"ars"`
-			Aud  float64 `json:"aud"`
-			Bch  float64 `json:"bch"`
-			Bdt  float64 `json:"bdt"`
-			Bhd  float64 `json:"bhd"`
-			Bmd  float64 `json:"bmd"`
-			Bnb  float64 `json:"bnb"`
-			Brl  float64 `json:"brl"`
-			Btc  float64 `json:"btc"`
-			Cad  float64 `json:"cad"`
-			Chf  float64 `json:"chf"`
-			Clp  float64 `json:"clp"`
-			Cny  float64 `json:"cny"`
-			Czk  float64 `json:"czk"`
-			Dkk  float64 `json:"dkk"`
-			Dot  float64 `json:"dot"`
-			Eos  float64 `json:"eos"`
-			Eth  float64 `json:"eth"`
-			Eur  float64 `json:"eur"`
-			Gbp  float64 `json:"gbp"`
-			Gel  float64 `json:"gel"`
-			Hkd  float64 `json:"hkd"`
-			Huf  float64 `json:"huf"`
-			Idr  float64 `json:"idr"`
-			Ils  float64 `json:"ils"`
-			Inr  float64 `json:"inr"`
-			Jpy  float64 `json:"jpy"`
-			Krw  float64 `json:"krw"`
-			Kwd  float64 `json:"kwd"`
-			Lkr  float64 `json:"lkr"`
-			Ltc  float64 `json:"ltc"`
-			Mmk  float64 `json:"mmk"`
-			Mxn  float64 `json:"mxn"`
-			Myr  float64 `json:"myr"`
-			Ngn  float64 `json:"ngn"`
-			Nok  float64 `json:"nok"`
-			Nzd  float64 `json:"nzd"`
-			Php  float64 `json:"php"`
-			Pkr  float64 `json:"pkr"`
-			Pln  float64 `json:"pln"`
-			Rub  float64 `json:"rub"`
-			Sar  float64 `json:"sar"`
-			Sek  float64 `json:"sek"`
-			Sgd  float64 `json:"sgd"`
-			Thb  float64 `json:"thb"`
-			Try  float64 `json:"try"`
-			Twd  float64 `json:"twd"`
-			Uah  float64 `json:"uah"`
-			Usd  float64 `json:"usd"`
-			Vef  float64 `json:"vef"`
-			Vnd  float64 `json:"vnd"`
-			Xag  float64 `json:"xag"`
-			Xau  float64 `json:"xau"`
-			Xdr  float64 `json:"xdr"`
-			Xlm  float64 `json:"xlm"`
-			Xrp  float64 `json:"xrp"`
-			Yfi  float64 `json:"yfi"`
-			Zar  float64 `json:"zar"`
-			Bits float64 `json:"bits"`
-			Link float64 `json:"link"`
-			Sats float64 `json:"sats"`
-		} `json:"current_price"`
-    } `json:"market_data"`
-}
 
 type Coin struct {
     Denom  string `json:"denom"`
     Amount string `json:"amount"`
 }
+type SupplyResponse struct {
+    Amount Coin `json:"amount"`
+}
+type BalanceResponse struct {
+    Balance Coin `json:"balance"`
+}
 
 //{\"@type\":\"/starnamed.x.starname.v1beta1.Account\",\"domain\":\"me\",\"name\":\"observer-test\",\"owner\":\"star15k7tssu0wyrfq57zj7ye297n50ew3sffy25me8\",\"broker\":\"\",\"valid_until\":\"1738468896\",\"resources\":[],\"certificates\":[],\"metadata_uri\":\"\"}
 
@@ -138,44 +98,130 @@ type EscrowObject struct {
     Domain     string `json:"domain"`
     Name       string `json:"name"`
     Owner      string `json:"owner"`
+    Admin      string `json:"admin"`
     Broker     string `json:"broker"`
     ValidUntil string `json:"valid_until"`
 }
+type Escrow struct {
+    ID       string       `json:"id"`
+    Seller   string       `json:"seller"`
+    Object   EscrowObject `json:"object"`
+    Price    []Coin       `json:"price"`
+    Deadline string       `json:"deadline"`
+}
+type EscrowResponse struct {
+    Escrow Escrow `json:"escrow"`
+}
+
+type StarnameResource struct {
+    URI      string `json:"uri"`
+    Resource string `json:"resource"`
+}
+type StarnameAccountResponse struct {
+    Account struct {
+        Domain      string             `json:"domain"`
+        Name        string             `json:"name"`
+        Owner       string             `json:"owner"`
+        ValidUntil  string             `json:"valid_until"`
+        Resources   []StarnameResource `json:"resources"`
+        MetadataURI string             `json:"metadata_uri"`
+    } `json:"account"`
+}
+type StarnameDomainResponse struct {
+    Domain struct {
+        Name       string `json:"name"`
+        Admin      string `json:"admin"`
+        ValidUntil string `json:"valid_until"`
+    } `json:"domain"`
+}
+// Tx body messages of MsgReplaceAccountResources and MsgReplaceAccountMetadata
+type StarnameReplaceMsg struct {
+    Domain         string             `json:"domain"`
+    Name           string             `json:"name"`
+    Owner          string             `json:"owner"`
+    NewResources   []StarnameResource `json:"new_resources"`
+    NewMetadataURI string             `json:"new_metadata_uri"`
+}
 
 type WebsocketResponse struct {
     Result struct {
-        Events struct {
-            MessageAction                  []string `json:"message.action"`
-            TransferSender                 []string `json:"transfer.sender"`
-            TransferRecipient              []string `json:"transfer.recipient"`
-            IBCTransferSender              []string `json:"ibc_transfer.sender"`
-            IBCTransferRecipient           []string `json:"ibc_transfer.receiver"`
-            IBCForeignSender               []string `json:"fungible_token_packet.sender"`
-            TransferAmount                 []string `json:"transfer.amount"`
-            TxHash                         []string `json:"tx.hash"`
-            WithdrawRewardsValidator       []string `json:"withdraw_rewards.validator"`
-            WithdrawRewardsDelegator       []string `json:"withdraw_rewards.delegator"`
-            WithdrawRewardsAmount          []string `json:"withdraw_rewards.amount"`
-            WithdrawCommissionAmount       []string `json:"withdraw_commission.amount"`
-            MessageSender                  []string `json:"message.sender"`
-            DelegateAmount                 []string `json:"delegate.amount"`
-            DelegateValidator              []string `json:"delegate.validator"`
-            UnbondValidator                []string `json:"unbond.validator"`
-            UnbondAmount                   []string `json:"unbond.amount"`
-            RedelegateSourceValidator      []string `json:"redelegate.source_validator"`
-            RedelegateDestinationValidator []string `json:"redelegate.destination_validator"`
-            RedelegateAmount               []string `json:"redelegate.amount"`
-            // Starname
-            AccountName        []string `json:"message.account_name"`
-            DomainName         []string `json:"message.domain_name"`
-            Registerer         []string `json:"message.registerer"`
-            NewAccountOwner    []string `json:"message.new_account_owner"`
-            NewDomainOwner     []string `json:"message.new_domain_owner"`
-            CreateEscrowPrice  []string `json:"starnamed.x.escrow.v1beta1.EventCreatedEscrow.price"`
-            CreateEscrowObject []string `json:"starnamed.x.escrow.v1beta1.EventCreatedEscrow.object"`
-        } `json:"events"`
+        Data   struct {
+            Value struct {
+                TxResult struct {
+                    // The protobuf encoded TX
+                    Tx []byte `json:"tx"`
+                } `json:"TxResult"`
+            } `json:"value"`
+        } `json:"data"`
+        Events Events `json:"events"`
+        // Every event attribute of the TX, used by the custom message types
+        Raw    map[string][]string `json:"-"`
     } `json:"result"`
 }
+// The event attributes of a TX, keyed by their event type and attribute name
+type Events struct {
+    MessageAction                  []string `json:"message.action"`
+    TransferSender                 []string `json:"transfer.sender"`
+    TransferRecipient              []string `json:"transfer.recipient"`
+    IBCTransferSender              []string `json:"ibc_transfer.sender"`
+    IBCTransferRecipient           []string `json:"ibc_transfer.receiver"`
+    IBCForeignSender               []string `json:"fungible_token_packet.sender"`
+    TransferAmount                 []string `json:"transfer.amount"`
+    TxHash                         []string `json:"tx.hash"`
+    WithdrawRewardsValidator       []string `json:"withdraw_rewards.validator"`
+    WithdrawRewardsDelegator       []string `json:"withdraw_rewards.delegator"`
+    WithdrawRewardsAmount          []string `json:"withdraw_rewards.amount"`
+    WithdrawCommissionAmount       []string `json:"withdraw_commission.amount"`
+    MessageSender                  []string `json:"message.sender"`
+    TxHeight                       []string `json:"tx.height"`
+    DelegateAmount                 []string `json:"delegate.amount"`
+    DelegateValidator              []string `json:"delegate.validator"`
+    UnbondValidator                []string `json:"unbond.validator"`
+    UnbondAmount                   []string `json:"unbond.amount"`
+    RedelegateSourceValidator      []string `json:"redelegate.source_validator"`
+    RedelegateDestinationValidator []string `json:"redelegate.destination_validator"`
+    RedelegateAmount               []string `json:"redelegate.amount"`
+    // IBC Packets
+    SendPacketSequence             []string `json:"send_packet.packet_sequence"`
+    SendPacketSrcChannel           []string `json:"send_packet.packet_src_channel"`
+    SendPacketData                 []string `json:"send_packet.packet_data"`
+    RecvPacketDstChannel           []string `json:"recv_packet.packet_dst_channel"`
+    TimeoutPacketSequence          []string `json:"timeout_packet.packet_sequence"`
+    TimeoutPacketSrcChannel        []string `json:"timeout_packet.packet_src_channel"`
+    TimeoutRefundReceiver          []string `json:"timeout.refund_receiver"`
+    TimeoutRefundDenom             []string `json:"timeout.refund_denom"`
+    TimeoutRefundAmount            []string `json:"timeout.refund_amount"`
+    AckPacketSequence              []string `json:"acknowledge_packet.packet_sequence"`
+    AckPacketSrcChannel            []string `json:"acknowledge_packet.packet_src_channel"`
+    IBCPacketReceiver              []string `json:"fungible_token_packet.receiver"`
+    IBCPacketDenom                 []string `json:"fungible_token_packet.denom"`
+    IBCPacketAmount                []string `json:"fungible_token_packet.amount"`
+    IBCPacketAck                   []string `json:"fungible_token_packet.acknowledgement"`
+    IBCPacketMemo                  []string `json:"fungible_token_packet.memo"`
+    // Supply
+    BurnBurner                     []string `json:"burn.burner"`
+    BurnAmount                     []string `json:"burn.amount"`
+    CoinbaseMinter                 []string `json:"coinbase.minter"`
+    CoinbaseAmount                 []string `json:"coinbase.amount"`
+    // Starname
+    AccountName        []string `json:"message.account_name"`
+    DomainName         []string `json:"message.domain_name"`
+    Registerer         []string `json:"message.registerer"`
+    NewAccountOwner    []string `json:"message.new_account_owner"`
+    NewDomainOwner     []string `json:"message.new_domain_owner"`
+    CreateEscrowID       []string `json:"starnamed.x.escrow.v1beta1.EventCreatedEscrow.id"`
+    CreateEscrowSeller   []string `json:"starnamed.x.escrow.v1beta1.EventCreatedEscrow.seller"`
+    CreateEscrowPrice    []string `json:"starnamed.x.escrow.v1beta1.EventCreatedEscrow.price"`
+    CreateEscrowObject   []string `json:"starnamed.x.escrow.v1beta1.EventCreatedEscrow.object"`
+    CreateEscrowDeadline []string `json:"starnamed.x.escrow.v1beta1.EventCreatedEscrow.deadline"`
+    UpdateEscrowID       []string `json:"starnamed.x.escrow.v1beta1.EventUpdatedEscrow.id"`
+    UpdateEscrowSeller   []string `json:"starnamed.x.escrow.v1beta1.EventUpdatedEscrow.new_seller"`
+    UpdateEscrowPrice    []string `json:"starnamed.x.escrow.v1beta1.EventUpdatedEscrow.new_price"`
+    UpdateEscrowDeadline []string `json:"starnamed.x.escrow.v1beta1.EventUpdatedEscrow.new_deadline"`
+    CompleteEscrowID     []string `json:"starnamed.x.escrow.v1beta1.EventCompletedEscrow.id"`
+    CompleteEscrowBuyer  []string `json:"starnamed.x.escrow.v1beta1.EventCompletedEscrow.buyer"`
+    RefundEscrowID       []string `json:"starnamed.x.escrow.v1beta1.EventRefundedEscrow.id"`
+}
 type ValidatorResponse struct {
     Validators []struct {
         OperatorAddress string `json:"operator_address"`
@@ -212,8 +258,30 @@ type ValidatorResponse struct {
     } `json:"pagination"`
 }
 
+// Queries the URL, at the block height unless it is empty, and unmarshals the json response into the container
+type Fetcher func(url string, height string, container interface{}) error
+
+// The fetcher of getData and getDataAtHeight, testFilter replaces it to run without the network
+var fetch Fetcher = fetchHTTP
+
+// Queries the URL and unmarshals the json response into the container
 func getData(url string, container interface{}) error {
-    resp, err := http.Get(url)
+    return fetch(url, "", container)
+}
+// Same as getData, but queries the state of the chain at the given block height
+func getDataAtHeight(url string, height string, container interface{}) error {
+    return fetch(url, height, container)
+}
+// Queries the URL over HTTP, the block height is sent in the x-cosmos-block-height header
+func fetchHTTP(url string, height string, container interface{}) error {
+    req, err := http.NewRequest(http.MethodGet, url, nil)
+    if err != nil {
+        return errors.Join(err, errors.New("Failed to create Request for: "+url))
+    }
+    if height != "" {
+        req.Header.Set("x-cosmos-block-height", height)
+    }
+    resp, err := http.DefaultClient.Do(req)
     if err != nil {
         return errors.Join(err, errors.New("Failed to get Reponse Information from: "+url))
     }
@@ -227,26 +295,23 @@ func getData(url string, container interface{}) error {
     }
     return nil
 }
-// TODO create an autoRefresh function specifically for coin gecko, which handles delays/rate limits
-// and discards responses if the data is 0. Coin Gecko's rate limit doesnt block the URL, instead it
-// returns garbage data (0's)
-func autoRefresh(url string, container interface{}) {
-    delay := time.Duration(300) * time.Second
-    // SLUG this is a cheesy fix
-    // Stagger the coin gecko responses
-    if strings.Contains(url, "coingecko") {
-        delay = time.Duration(rand.Intn(600-300+1)+300) * time.Second
-    }
-    ticker := time.NewTicker(delay)
-    if err := getData(url, container); err != nil {
-        log.Println(color.YellowString("Failed to get AutoRefresh Data: ", err))
+// Refreshes the data from the URL every 5 minutes, calling refreshed after each successful refresh
+func autoRefresh(url string, container interface{}, refreshed ...func()) {
+    ticker := time.NewTicker(time.Duration(300) * time.Second)
+    refresh := func() {
+        if err := getData(url, container); err != nil {
+            log.Println(color.YellowString("Failed to get AutoRefresh Data: %v", err))
+            return
+        }
+        for _, f := range refreshed {
+            f()
+        }
     }
+    refresh()
     for {
         select {
         case <-ticker.C:
-            if err := getData(url, container); err != nil {
-                log.Println(color.YellowString("Failed to get AutoRefresh Data: ", err))
-            }
+            refresh()
         }
     }
 }
@@ -0,0 +1,230 @@
+package main
+
+import (
+    "encoding/json"
+    "log"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/fatih/color"
+)
+
+// How long to remember escrows without a known deadline for
+const escrowExpiry = 30 * 24 * time.Hour
+
+// Escrows seen by the websocket, keyed by their ID
+// Completed and refunded escrows are removed from the chain state, so the data from the
+// creation/update events is kept around to describe them later
+var escrows = struct {
+    sync.Mutex
+    m map[string]cachedEscrow
+}{m: map[string]cachedEscrow{}}
+
+// An escrow in the cache, and when it can be forgotten
+type cachedEscrow struct {
+    Escrow  Escrow
+    Expires time.Time
+}
+
+// Typed events (like the escrow events) have their attribute values JSON encoded,
+// E.G. "\"star1...\"" becomes star1...
+func unquoteEvent(value string) string {
+    var str string
+    if err := json.Unmarshal([]byte(value), &str); err != nil {
+        return value
+    }
+    return str
+}
+
+// Merges the data from a created or updated escrow event into the escrow, and caches the result
+// Empty values are ignored, so an update event only overwrites the fields that changed
+// Escrows past their deadline are dropped at the same time, they can still be fetched from the chain state
+func storeEscrow(escrow Escrow, seller string, price string, object string, deadline string) Escrow {
+    if seller = unquoteEvent(seller); seller != "" {
+        escrow.Seller = seller
+    }
+    if deadline = unquoteEvent(deadline); deadline != "" {
+        escrow.Deadline = deadline
+    }
+    var coins []Coin
+    if err := json.Unmarshal([]byte(price), &coins); err == nil && len(coins) > 0 {
+        escrow.Price = coins
+    }
+    var obj EscrowObject
+    if err := json.Unmarshal([]byte(object), &obj); err == nil && obj.Type != "" {
+        escrow.Object = obj
+    }
+    expires := time.Now().Add(escrowExpiry)
+    if t, ok := escrowDeadline(escrow); ok {
+        expires = t
+    }
+    escrows.Lock()
+    defer escrows.Unlock()
+    for id, cached := range escrows.m {
+        if time.Now().After(cached.Expires) {
+            delete(escrows.m, id)
+        }
+    }
+    escrows.m[escrow.ID] = cachedEscrow{Escrow: escrow, Expires: expires}
+    return escrow
+}
+
+// Returns the deadline of the escrow, a unix timestamp
+func escrowDeadline(escrow Escrow) (time.Time, bool) {
+    unix, err := strconv.ParseInt(escrow.Deadline, 10, 64)
+    if err != nil {
+        return time.Time{}, false
+    }
+    return time.Unix(unix, 0), true
+}
+
+// Returns the escrow for the given ID, from the cache if it has been seen before,
+// otherwise from the chain state at the block before the given height
+func getEscrow(id string, height string) (Escrow, bool) {
+    id = unquoteEvent(id)
+    escrows.Lock()
+    cached, ok := escrows.m[id]
+    escrows.Unlock()
+    if ok {
+        return cached.Escrow, true
+    }
+    var res EscrowResponse
+    h, err := strconv.Atoi(height)
+    if err != nil {
+        return Escrow{}, false
+    }
+    err = getDataAtHeight(config.Connections.Rest + "starname/v1beta1/escrow/escrow/" + id, strconv.Itoa(h-1), &res)
+    if err != nil || res.Escrow.ID == "" {
+        log.Println(color.YellowString("Failed to get Escrow rest response: %v", err))
+        return Escrow{}, false
+    }
+    return res.Escrow, true
+}
+
+// Removes a completed or refunded escrow from the cache
+func forgetEscrow(id string) {
+    escrows.Lock()
+    defer escrows.Unlock()
+    delete(escrows.m, unquoteEvent(id))
+}
+
+// Returns the starname being sold by the escrow, name*domain for accounts and *domain for domains
+func mkStarname(obj EscrowObject) string {
+    if strings.HasSuffix(obj.Type, ".Domain") {
+        return "*" + obj.Name
+    }
+    return obj.Name + "*" + obj.Domain
+}
+
+// Returns the first coin of the list in the format used by the events, E.G. 1000000uiov
+func coinsToAmount(coins []Coin) string {
+    if len(coins) < 1 {
+        return ""
+    }
+    return coins[0].Amount + coins[0].Denom
+}
+
+// Converts a unix timestamp like the starnames' valid_until to a readable date
+func formatExpiry(timestamp string) string {
+    unix, err := strconv.ParseInt(timestamp, 10, 64)
+    if err != nil {
+        return "Unknown"
+    }
+    return time.Unix(unix, 0).UTC().Format("2006-01-02")
+}
+
+// Adds the starnames' product fee to the document, linked to the transaction
+// The first transfer of the TX is the gas fee, so the product fee is the second one. If no fee was paid,
+// links the transaction hash instead
+func addStarnameFee(doc *Document, hash string, amounts []string) {
+    if len(amounts) < 2 {
+        doc.AddGroup("TX", hashText(hash))
+        return
+    }
+    doc.AddGroup("Fee", amountText(hash, amounts[1]))
+}
+
+// Returns the current expiry of the starname account, or of the domain if the name is empty
+func getStarnameExpiry(name string, domain string) string {
+    if name == "" {
+        var res StarnameDomainResponse
+        if err := getData(config.Connections.Rest + "starname/v1beta1/domain/" + domain, &res); err != nil {
+            log.Println(color.YellowString("Failed to get Starname domain rest response: %v", err))
+            return "Unknown"
+        }
+        return formatExpiry(res.Domain.ValidUntil)
+    }
+    var res StarnameAccountResponse
+    if err := getData(config.Connections.Rest + "starname/v1beta1/account/" + name + "*" + domain, &res); err != nil {
+        log.Println(color.YellowString("Failed to get Starname account rest response: %v", err))
+        return "Unknown"
+    }
+    return formatExpiry(res.Account.ValidUntil)
+}
+
+// Returns the resources of the starname account at the block before the given height
+func getPreviousResources(name string, domain string, height string) ([]StarnameResource, error) {
+    var res StarnameAccountResponse
+    h, err := strconv.Atoi(height)
+    if err != nil {
+        return nil, err
+    }
+    url := config.Connections.Rest + "starname/v1beta1/account/" + name + "*" + domain
+    if err := getDataAtHeight(url, strconv.Itoa(h-1), &res); err != nil {
+        return nil, err
+    }
+    return res.Account.Resources, nil
+}
+
+// Compares the old and new resources of a starname account, returning the ones added and removed
+func diffResources(old []StarnameResource, current []StarnameResource) ([]StarnameResource, []StarnameResource) {
+    var added, removed []StarnameResource
+    contains := func(list []StarnameResource, res StarnameResource) bool {
+        for _, r := range list {
+            if r == res {
+                return true
+            }
+        }
+        return false
+    }
+    for _, res := range current {
+        if !contains(old, res) {
+            added = append(added, res)
+        }
+    }
+    for _, res := range old {
+        if !contains(current, res) {
+            removed = append(removed, res)
+        }
+    }
+    return added, removed
+}
+
+// Formats a linked address, E.G. asset:btc with bc1... becomes BTC: bc1...
+func mkResource(res StarnameResource) string {
+    uri := res.URI
+    if i := strings.Index(uri, ":"); i >= 0 {
+        uri = uri[i+1:]
+    }
+    return strings.ToUpper(uri) + ": " + res.Resource
+}
+
+// Formats a list of linked addresses
+func mkResources(resources []StarnameResource) []string {
+    var list []string
+    for _, res := range resources {
+        list = append(list, mkResource(res))
+    }
+    return list
+}
+
+// Formats a list of linked addresses as the lines of a field
+func mkResourceLines(resources []StarnameResource) [][]Text {
+    var lines [][]Text
+    for _, res := range resources {
+        lines = append(lines, []Text{plain(mkResource(res))})
+    }
+    return lines
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Running totals of the chains' coin burned and minted since reFUNDScan started, and at the last supply report
+// Module burns lower the supply, unlike transfers to burn addresses, so they are also counted on their own
+var supply = struct {
+    sync.Mutex
+    Burned         big.Int
+    ModuleBurned   big.Int
+    Minted         big.Int
+    ReportedBurned big.Int
+    ReportedModuleBurned big.Int
+    ReportedMinted big.Int
+    ReportedSupply big.Int
+}{}
+
+// Returns true if the address is one of the configured burn addresses
+func isBurnAddress(addr string) bool {
+    for _, burn := range config.Config.SupplyConfig.BurnAddresses {
+        if burn == addr {
+            return true
+        }
+    }
+    return false
+}
+
+// Adds the burns and mints of the chains' coin in the TX to the running totals, and returns the
+// messages announcing them
+func trackSupply(events WebsocketResponse) []MessageResponse {
+    var msgs []MessageResponse
+    ev := events.Result.Events
+    if len(ev.TxHash) < 1 {
+        return msgs
+    }
+    // Amounts can be lists of coins, only the chains' coin is tracked
+    burn := func(burner string, amounts string, module bool) {
+        for _, amount := range splitCoins(amounts) {
+            amt, denom := splitAmountDenom(amount)
+            if denom != config.Chain.Denom {
+                continue
+            }
+            supply.Lock()
+            supply.Burned.Add(&supply.Burned, amt)
+            if module {
+                supply.ModuleBurned.Add(&supply.ModuleBurned, amt)
+            }
+            total := new(big.Int).Set(&supply.Burned)
+            supply.Unlock()
+            if msg, ok := mkSupplyMessage("Burns", burner, amount, total, ev.TxHash[0]); ok {
+                msgs = append(msgs, msg)
+            }
+        }
+    }
+    // Burned by modules, E.G. governance deposits
+    for i, burner := range ev.BurnBurner {
+        if i < len(ev.BurnAmount) {
+            burn(burner, ev.BurnAmount[i], true)
+        }
+    }
+    // Sent to a burn address
+    for i, recipient := range ev.TransferRecipient {
+        if isBurnAddress(recipient) && i < len(ev.TransferSender) && i < len(ev.TransferAmount) {
+            burn(ev.TransferSender[i], ev.TransferAmount[i], false)
+        }
+    }
+    // Only mints of the TX, the inflation minted at the start of each block has no TX and is part of the
+    // minted amount of the supply report instead
+    for i, minter := range ev.CoinbaseMinter {
+        if i >= len(ev.CoinbaseAmount) {
+            break
+        }
+        for _, amount := range splitCoins(ev.CoinbaseAmount[i]) {
+            amt, denom := splitAmountDenom(amount)
+            if denom != config.Chain.Denom {
+                continue
+            }
+            supply.Lock()
+            supply.Minted.Add(&supply.Minted, amt)
+            total := new(big.Int).Set(&supply.Minted)
+            supply.Unlock()
+            if msg, ok := mkSupplyMessage("Mints", minter, amount, total, ev.TxHash[0]); ok {
+                msgs = append(msgs, msg)
+            }
+        }
+    }
+    return msgs
+}
+
+// Returns the message for a burn or mint, returns false if the message type is disabled or filtered
+func mkSupplyMessage(typeName string, addr string, amount string, total *big.Int, hash string) (MessageResponse, bool) {
+    var msg MessageResponse
+    msg.TypeName = typeName
+    totalAmount := total.String() + config.Chain.Denom
+    switch typeName {
+    case "Burns":
+        msg.Type = config.Config.MessagesConfig.Burns
+        msg.Data = MessageData{"burner": addr, "amount": amount, "total": totalAmount, "hash": hash}
+        msg.Doc.Title = "🔥 Burn 🔥"
+        msg.Doc.Add("Burner", accountText(addr))
+        msg.Doc.Add("Amount", amountText(hash, amount))
+        msg.Doc.Add("Total Burned", coinsText(totalAmount))
+    case "Mints":
+        msg.Type = config.Config.MessagesConfig.Mints
+        msg.Data = MessageData{"minter": addr, "amount": amount, "total": totalAmount, "hash": hash}
+        msg.Doc.Title = "🪙 Mint 🪙"
+        msg.Doc.Add("Minter", accountText(addr))
+        msg.Doc.Add("Amount", amountText(hash, amount))
+        msg.Doc.Add("Total Minted", coinsText(totalAmount))
+    }
+    msg.Doc.Hash = hash
+    msg.Amount = amount
+    if !msg.Type.Enabled || !isAllowedAmount(msg) || !renderMessage(&msg) {
+        return msg, false
+    }
+    return msg, true
+}
+
+// Returns the total supply of the chains' coin
+func getSupply() (*big.Int, error) {
+    var res SupplyResponse
+    err := getData(config.Connections.Rest + "cosmos/bank/v1beta1/supply/by_denom?denom=" + config.Chain.Denom, &res)
+    if err != nil || res.Amount.Amount == "" {
+        // Older versions of the SDK
+        err = getData(config.Connections.Rest + "cosmos/bank/v1beta1/supply/" + config.Chain.Denom, &res)
+    }
+    if err != nil {
+        return nil, err
+    }
+    amount, _ := splitAmountDenom(res.Amount.Amount + res.Amount.Denom)
+    return amount, nil
+}
+
+// Returns the sum of the balances of the burn addresses
+func getBurnBalances() *big.Int {
+    total := new(big.Int)
+    for _, addr := range config.Config.SupplyConfig.BurnAddresses {
+        var res BalanceResponse
+        err := getData(config.Connections.Rest + "cosmos/bank/v1beta1/balances/" + addr + "/by_denom?denom=" + config.Chain.Denom, &res)
+        if err != nil {
+            log.Println(color.YellowString("Failed to get burn address balance: %v", err))
+            continue
+        }
+        amount, _ := splitAmountDenom(res.Balance.Amount + res.Balance.Denom)
+        total.Add(total, amount)
+    }
+    return total
+}
+
+// Formats a change in amount of the chains' coin, E.G. -1000nund becomes - 0.00 FUND (0.00 USD)
+func mkSupplyChange(change *big.Int) []Text {
+    sign := "+"
+    if change.Sign() < 0 {
+        sign = "-"
+    }
+    return []Text{plain(sign + " "), coinsText(new(big.Int).Abs(change).String() + config.Chain.Denom)}
+}
+
+// Sends a supply report to the channel every report interval
+func autoReport(resp chan MessageResponse) {
+    ticker := time.NewTicker(time.Duration(config.Config.SupplyConfig.ReportInterval) * time.Hour)
+    // The first query only sets the supply to compare the reports to
+    if total, err := getSupply(); err != nil {
+        log.Println(color.YellowString("Failed to get Supply Data: %v", err))
+    } else {
+        supply.Lock()
+        supply.ReportedSupply.Set(total)
+        supply.Unlock()
+    }
+    for {
+        select {
+        case <-ticker.C:
+            total, err := getSupply()
+            if err != nil {
+                log.Println(color.YellowString("Failed to get Supply Data: %v", err))
+                continue
+            }
+            supply.Lock()
+            burned := new(big.Int).Sub(&supply.Burned, &supply.ReportedBurned)
+            minted := new(big.Int).Sub(&supply.Minted, &supply.ReportedMinted)
+            moduleBurned := new(big.Int).Sub(&supply.ModuleBurned, &supply.ReportedModuleBurned)
+            change := new(big.Int).Sub(total, &supply.ReportedSupply)
+            known := supply.ReportedSupply.Sign() != 0
+            // Inflation is minted outside of TXs, so everything minted is derived from the change of the supply
+            // and the burns which lowered it, falling back to the mints of TXs for the first report
+            if known {
+                minted = new(big.Int).Add(change, moduleBurned)
+            }
+            supply.ReportedBurned.Set(&supply.Burned)
+            supply.ReportedModuleBurned.Set(&supply.ModuleBurned)
+            supply.ReportedMinted.Set(&supply.Minted)
+            supply.ReportedSupply.Set(total)
+            supply.Unlock()
+
+            var msg MessageResponse
+            msg.Type = config.Config.MessagesConfig.SupplyReport
+            msg.TypeName = "SupplyReport"
+            msg.Data = MessageData{
+                "supply": total.String() + config.Chain.Denom,
+                "change": "",
+                "burned": plainString(mkSupplyChange(new(big.Int).Neg(burned))),
+                "minted": plainString(mkSupplyChange(minted)),
+                "burn_addresses": "",
+                "period": strconv.Itoa(config.Config.SupplyConfig.ReportInterval),
+            }
+            msg.Doc.Title = "📊 Supply Report 📊"
+            msg.Doc.Add("Total Supply", coinsText(total.String() + config.Chain.Denom))
+            if known {
+                msg.Doc.Add("Change", mkSupplyChange(change)...)
+                msg.Data["change"] = plainString(mkSupplyChange(change))
+            }
+            msg.Doc.AddGroup("Burned", mkSupplyChange(new(big.Int).Neg(burned))...)
+            msg.Doc.Add("Minted", mkSupplyChange(minted)...)
+            if len(config.Config.SupplyConfig.BurnAddresses) > 0 {
+                balances := getBurnBalances().String() + config.Chain.Denom
+                msg.Doc.AddGroup("Burn Addresses", coinsText(balances))
+                msg.Data["burn_addresses"] = balances
+            }
+            msg.Doc.AddGroup("Period", plain(fmt.Sprintf("Last %d hours", config.Config.SupplyConfig.ReportInterval)))
+            if !renderMessage(&msg) {
+                continue
+            }
+            if msg.Type.Enabled && isAllowedMessage(msg) {
+                resp <- msg
+            }
+        }
+    }
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "log"
+    "strings"
+    "text/template"
+    "text/template/parse"
+
+    "github.com/fatih/color"
+)
+
+// The fields of a message, available to its types' template
+type MessageData map[string]interface{}
+
+// A message type, and the fields its template can use
+// List fields are named list.field for lists of objects, or list[] for lists of values
+type MessageType struct {
+    Config *MessageConfig
+    Fields []string
+}
+
+// Message types keyed by their type name
+var messageTypes = map[string]MessageType{}
+
+// Compiled templates of the message types that have one, keyed by their type name
+var templates = map[string]*template.Template{}
+
+// Adds a message type, with the fields its template can use
+func registerType(typeName string, cfg *MessageConfig, fields ...string) {
+    messageTypes[typeName] = MessageType{Config: cfg, Fields: fields}
+}
+
+// Text already formatted for the markup of the client, which isn't escaped again when the template prints it
+type markedUp string
+
+// Helpers available to the templates, formatting their output for the markup of the client and amounts in the currency
+func templateFuncs(m Markup, currency string) template.FuncMap {
+    return template.FuncMap{
+        "account":  func(addr string) markedUp { return markedUp(renderText(m, accountText(addr))) },
+        "amount":   func(amount string) markedUp { return markedUp(m.Escape(denomToAmount(amount, currency))) },
+        "txlink":   func(hash string, amount string) markedUp {
+            return markedUp(renderText(m, amountText(hash, amount).inCurrency(currency)))
+        },
+        "hashlink": func(hash string) markedUp { return markedUp(renderText(m, hashText(hash))) },
+        "bold":     func(text string) markedUp { return markedUp(m.Bold(m.Escape(text))) },
+        "escape":   func(value interface{}) string {
+            if text, ok := value.(markedUp); ok {
+                return string(text)
+            }
+            return m.Escape(fmt.Sprint(value))
+        },
+    }
+}
+
+// Helpers with the same signatures that don't query anything, used to validate the templates
+func sampleFuncs() template.FuncMap {
+    return template.FuncMap{
+        "account":  func(addr string) markedUp { return markedUp(addr) },
+        "amount":   func(amount string) markedUp { return markedUp(amount) },
+        "txlink":   func(hash string, amount string) markedUp { return markedUp(amount) },
+        "hashlink": func(hash string) markedUp { return markedUp(hash) },
+        "bold":     func(text string) markedUp { return markedUp(text) },
+        "escape":   func(value interface{}) string { return fmt.Sprint(value) },
+    }
+}
+
+// Parses a message template with the formatting helpers
+// Everything the template prints is passed through escape, so the fields are escaped for the client
+func parseTemplate(name string, text string) (*template.Template, error) {
+    tmpl, err := template.New(name).Funcs(sampleFuncs()).Parse(text)
+    if err != nil {
+        return nil, err
+    }
+    for _, t := range tmpl.Templates() {
+        if t.Tree != nil {
+            escapeActions(t.Tree, t.Tree.Root)
+        }
+    }
+    return tmpl, nil
+}
+
+// Appends escape to the pipeline of every action printing a value
+func escapeActions(tree *parse.Tree, node parse.Node) {
+    switch n := node.(type) {
+    case *parse.ListNode:
+        if n == nil {
+            return
+        }
+        for _, child := range n.Nodes {
+            escapeActions(tree, child)
+        }
+    case *parse.ActionNode:
+        if len(n.Pipe.Decl) == 0 {
+            escape := parse.NewIdentifier("escape").SetPos(n.Pos).SetTree(tree)
+            n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{escape}})
+        }
+    case *parse.IfNode:
+        escapeActions(tree, n.List)
+        escapeActions(tree, n.ElseList)
+    case *parse.RangeNode:
+        escapeActions(tree, n.List)
+        escapeActions(tree, n.ElseList)
+    case *parse.WithNode:
+        escapeActions(tree, n.List)
+        escapeActions(tree, n.ElseList)
+    }
+}
+
+// Returns example data containing every field, E.G. validators.amount becomes
+// validators: [{amount: "sample"}]
+func sampleData(fields []string) MessageData {
+    data := MessageData{}
+    for _, field := range fields {
+        if list, ok := strings.CutSuffix(field, "[]"); ok {
+            data[list] = []string{"sample"}
+        } else if list, item, ok := strings.Cut(field, "."); ok {
+            items, _ := data[list].([]map[string]string)
+            if len(items) == 0 {
+                items = []map[string]string{{}}
+            }
+            items[0][item] = "sample"
+            data[list] = items
+        } else {
+            data[field] = "sample"
+        }
+    }
+    return data
+}
+
+// Executes the template against example data, returns an error if it uses a field the message type doesn't have
+func checkTemplate(tmpl *template.Template, fields []string) error {
+    check, err := tmpl.Clone()
+    if err != nil {
+        return err
+    }
+    return check.Funcs(sampleFuncs()).Option("missingkey=error").Execute(io.Discard, sampleData(fields))
+}
+
+// Adds the explorer links, the sources of the prices of its amount and the template of the messages' type to its
+// document, and renders the document as markdown, catching templates which fail to render. Returns false if the
+// template failed to render
+func renderMessage(msg *MessageResponse) bool {
+    addExplorerLinks(&msg.Doc)
+    // Price alerts set the sources themselves
+    if len(msg.Doc.PriceSources) == 0 {
+        msg.Doc.PriceSources = amountSources(msg.Amount)
+    }
+    if tmpl, ok := templates[msg.TypeName]; ok {
+        msg.Doc.Template = tmpl
+        msg.Doc.Data = msg.Data
+    }
+    text, err := msg.Doc.Render(markdown{})
+    if err != nil {
+        log.Println(color.YellowString("Failed to render template for message type " + msg.TypeName + ": " + err.Error()))
+        return false
+    }
+    msg.Message = text
+    return true
+}